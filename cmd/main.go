@@ -1,24 +1,36 @@
 package main
 
 import (
+	"context"
+	"cruder/internal/config"
 	"cruder/internal/core"
 	"cruder/internal/repository"
+	"cruder/internal/service"
 	"log"
-	"os"
 )
 
 func main() {
-	dataSourceName := os.Getenv("POSTGRES_DSN")
-	if dataSourceName == "" {
-		dataSourceName = "host=localhost port=5432 user=postgres password=postgres dbname=cruderdb sslmode=disable"
+	appConfig, err := config.FromEnv()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	dbConnection, err := repository.NewPostgresConnection(dataSourceName)
+	dbConnection, err := repository.NewPostgresConnection(appConfig.PostgresDSN)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	_, httpRouterEngine := core.SetupAppLayers(dbConnection.DB(), os.Getenv("X_API_KEY"))
+	repositories, httpRouterEngine := core.SetupAppLayers(dbConnection.DB(), appConfig)
+
+	if appConfig.DeletedUsersRetention > 0 {
+		purger := service.NewDeletedUsersPurger(
+			repositories.Users, appConfig.DeletedUsersRetention, appConfig.PurgeInterval)
+		go purger.Run(context.Background())
+	}
+
+	go service.NewExpiredIdempotencyKeysPurger(repositories.IdempotencyKeys, appConfig.PurgeInterval).
+		Run(context.Background())
+
 	if err := httpRouterEngine.Run(); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
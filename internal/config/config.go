@@ -0,0 +1,257 @@
+package config
+
+import (
+	"cruder/internal/model"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultPostgresDSN = "host=localhost port=5432 user=postgres password=postgres dbname=cruderdb sslmode=disable"
+const defaultPurgeInterval = time.Hour
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+const defaultIdempotencyLease = time.Minute
+const defaultAPIKeyCacheTTL = 10 * time.Second
+const defaultJWKSRefreshInterval = time.Hour
+const defaultSignatureWindow = 5 * time.Minute
+
+// APIKey is a named credential for the X-API-Key header granting the listed scopes and the roles of the UserPolicy.
+// It is also the secret of the HMAC signed requests, which are the only ones accepted if SignatureRequired.
+type APIKey struct {
+	Name              string   `json:"name"`
+	Key               string   `json:"key"`
+	Scopes            []string `json:"scopes"`
+	Roles             []string `json:"roles"`
+	SignatureRequired bool     `json:"signature_required"`
+}
+
+type Config struct {
+	PostgresDSN string
+	// XApiKey is a single unnamed key granting every scope, kept for the deployments predating APIKeys.
+	XApiKey string
+	// APIKeys are the named keys, authentication is off when neither they, XApiKey nor stored keys exist.
+	// The anonymous caller is then denied the api-keys:admin scope, so the first stored key is minted with one of them.
+	APIKeys []APIKey
+	// APIKeyCacheTTL is how long a stored key is trusted without a lookup, so also how late a revocation may apply.
+	APIKeyCacheTTL time.Duration
+	// SignatureWindow is how far the timestamp of a signed request may be from now, and so how long its nonce is kept.
+	SignatureWindow time.Duration
+	// JWKSSource is the file or http(s) URL of the keys verifying the bearer tokens, which are refused without it.
+	JWKSSource          string
+	JWKSRefreshInterval time.Duration
+	JWTIssuer           string
+	JWTAudience         string
+	// JWTScopeMapping translates the values of the scope and scp claims, which are taken as scopes without it.
+	JWTScopeMapping map[string][]string
+	// UserPolicy restricts the user management by the roles of the principal, it is unrestricted without one.
+	UserPolicy *model.UserPolicy
+	// IdRouteDisabled switches off the deprecated GET /api/v1/users/id/:id lookup.
+	IdRouteDisabled bool
+	// IfMatchRequired makes PUT, PATCH and DELETE without an If-Match header fail with 428.
+	IfMatchRequired bool
+	// DeletedUsersRetention is how long soft-deleted users are kept before the purge, zero keeps them forever.
+	DeletedUsersRetention time.Duration
+	PurgeInterval         time.Duration
+	// DBTimeout bounds the database work of a single request, zero leaves it unbounded.
+	DBTimeout time.Duration
+	// IdempotencyKeyTTL is how long the response to a request with an Idempotency-Key is replayed.
+	IdempotencyKeyTTL time.Duration
+	// IdempotencyLease is how long a request with an Idempotency-Key holds it before a retry may take it over,
+	// zero holds it as long as IdempotencyKeyTTL.
+	IdempotencyLease time.Duration
+}
+
+func FromEnv() (Config, error) {
+	appConfig := Config{
+		PostgresDSN: os.Getenv("POSTGRES_DSN"),
+		XApiKey:     os.Getenv("X_API_KEY"),
+	}
+	if appConfig.PostgresDSN == "" {
+		appConfig.PostgresDSN = defaultPostgresDSN
+	}
+
+	var err error
+	if appConfig.APIKeys, err = apiKeysFromEnv("API_KEYS"); err != nil {
+		return appConfig, err
+	}
+	if appConfig.APIKeyCacheTTL, err = durationFromEnv("API_KEY_CACHE_TTL", defaultAPIKeyCacheTTL); err != nil {
+		return appConfig, err
+	}
+	if appConfig.SignatureWindow, err = durationFromEnv("SIGNATURE_WINDOW", defaultSignatureWindow); err != nil {
+		return appConfig, err
+	}
+	if appConfig.SignatureWindow == 0 {
+		return appConfig, fmt.Errorf("invalid SIGNATURE_WINDOW: must be positive")
+	}
+	if err = jwtFromEnv(&appConfig); err != nil {
+		return appConfig, err
+	}
+	if appConfig.UserPolicy, err = userPolicyFromEnv("POLICY_FILE"); err != nil {
+		return appConfig, err
+	}
+	for _, apiKey := range appConfig.APIKeys {
+		for _, role := range apiKey.Roles {
+			if !appConfig.UserPolicy.Defines(role) {
+				return appConfig, fmt.Errorf("invalid API_KEYS: role %s of key %s is not in POLICY_FILE", role, apiKey.Name)
+			}
+		}
+	}
+	if appConfig.IdRouteDisabled, err = boolFromEnv("ID_ROUTE_DISABLED"); err != nil {
+		return appConfig, err
+	}
+
+	if appConfig.IfMatchRequired, err = boolFromEnv("IF_MATCH_REQUIRED"); err != nil {
+		return appConfig, err
+	}
+	if appConfig.DeletedUsersRetention, err = durationFromEnv("DELETED_USERS_RETENTION", 0); err != nil {
+		return appConfig, err
+	}
+	if appConfig.PurgeInterval, err = durationFromEnv("PURGE_INTERVAL", defaultPurgeInterval); err != nil {
+		return appConfig, err
+	}
+	if appConfig.PurgeInterval == 0 {
+		return appConfig, fmt.Errorf("invalid PURGE_INTERVAL: must be positive")
+	}
+	if appConfig.DBTimeout, err = durationFromEnv("DB_TIMEOUT", 0); err != nil {
+		return appConfig, err
+	}
+	if appConfig.IdempotencyKeyTTL, err = durationFromEnv("IDEMPOTENCY_KEY_TTL", defaultIdempotencyKeyTTL); err != nil {
+		return appConfig, err
+	}
+	if appConfig.IdempotencyKeyTTL == 0 {
+		return appConfig, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL: must be positive")
+	}
+	// a request bounded by DB_TIMEOUT is surely over after a few of them
+	defaultLease := defaultIdempotencyLease
+	if appConfig.DBTimeout > 0 {
+		defaultLease = 3 * appConfig.DBTimeout
+	}
+	if appConfig.IdempotencyLease, err = durationFromEnv("IDEMPOTENCY_LEASE", defaultLease); err != nil {
+		return appConfig, err
+	}
+	if appConfig.IdempotencyLease == 0 {
+		return appConfig, fmt.Errorf("invalid IDEMPOTENCY_LEASE: must be positive")
+	}
+
+	return appConfig, nil
+}
+
+// apiKeysFromEnv reads a JSON array like [{"name": "support", "key": "...", "scopes": ["users:read"]}].
+func apiKeysFromEnv(name string) ([]APIKey, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	var apiKeys []APIKey
+	if err := json.Unmarshal([]byte(value), &apiKeys); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	names := map[string]bool{}
+	keys := map[string]bool{}
+	for _, apiKey := range apiKeys {
+		if apiKey.Name == "" || apiKey.Key == "" || names[apiKey.Name] || keys[apiKey.Key] {
+			return nil, fmt.Errorf("invalid %s: every key needs a unique name and a unique value", name)
+		}
+		// the name is recorded as the actor api-key:<name>
+		if utf8.RuneCountInString("api-key:"+apiKey.Name) > model.MaxActorLength {
+			return nil, fmt.Errorf("invalid %s: a key name is longer than %d characters", name, model.MaxActorLength-8)
+		}
+		names[apiKey.Name] = true
+		keys[apiKey.Key] = true
+
+		for _, scope := range apiKey.Scopes {
+			if !slices.Contains(model.AllScopes, scope) {
+				return nil, fmt.Errorf("invalid %s: unknown scope %q of key %s", name, scope, apiKey.Name)
+			}
+		}
+	}
+
+	return apiKeys, nil
+}
+
+func jwtFromEnv(appConfig *Config) error {
+	appConfig.JWKSSource = os.Getenv("JWKS_SOURCE")
+	if appConfig.JWKSSource == "" {
+		return nil
+	}
+
+	appConfig.JWTIssuer = os.Getenv("JWT_ISSUER")
+	appConfig.JWTAudience = os.Getenv("JWT_AUDIENCE")
+	if appConfig.JWTIssuer == "" || appConfig.JWTAudience == "" {
+		return fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE are required along with JWKS_SOURCE")
+	}
+
+	var err error
+	if appConfig.JWKSRefreshInterval, err = durationFromEnv("JWKS_REFRESH_INTERVAL", defaultJWKSRefreshInterval); err != nil {
+		return err
+	}
+
+	if value := os.Getenv("JWT_SCOPE_MAPPING"); value != "" {
+		if err := json.Unmarshal([]byte(value), &appConfig.JWTScopeMapping); err != nil {
+			return fmt.Errorf("invalid JWT_SCOPE_MAPPING: %w", err)
+		}
+		for claimValue, scopes := range appConfig.JWTScopeMapping {
+			for _, scope := range scopes {
+				if !slices.Contains(model.AllScopes, scope) {
+					return fmt.Errorf("invalid JWT_SCOPE_MAPPING: unknown scope %q for %s", scope, claimValue)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// userPolicyFromEnv reads the JSON policy file like
+// {"roles": {"support": {"actions": ["read", "update"], "fields": ["full_name"]}}, "default_roles": ["support"]}.
+func userPolicyFromEnv(name string) (*model.UserPolicy, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	policy, err := model.ParseUserPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return policy, nil
+}
+
+func boolFromEnv(name string) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return parsed, nil
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, value)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"cruder/internal/controller/dto"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"cruder/internal/service"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	service service.APIKeyService
+}
+
+const invalidGracePeriodClientErrorValue = "invalid grace_period, a non-negative duration like 1h expected"
+
+func NewAPIKeyController(service service.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+func (c *APIKeyController) GetAllAPIKeys(ctx *gin.Context) {
+	keys, err := c.service.GetAll(ctx.Request.Context())
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	response := dto.APIKeyListResponse{Data: make([]dto.APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		response.Data = append(response.Data, toAPIKeyResponse(&key))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *APIKeyController) MintAPIKey(ctx *gin.Context) {
+	var key dto.APIKeyCreate
+	if err := ctx.ShouldBindJSON(&key); err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+		return
+	}
+
+	minted, err := c.service.Mint(ctx.Request.Context(), key)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	createMintedAPIKeyResponse(ctx, minted)
+}
+
+func (c *APIKeyController) RotateAPIKey(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	var gracePeriod time.Duration
+	if gracePeriodStr, present := ctx.GetQuery("grace_period"); present {
+		if gracePeriod, err = time.ParseDuration(gracePeriodStr); err != nil || gracePeriod < 0 {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidGracePeriodClientErrorValue)
+			return
+		}
+	}
+
+	minted, err := c.service.Rotate(ctx.Request.Context(), aUuid, gracePeriod)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	createMintedAPIKeyResponse(ctx, minted)
+}
+
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	if err := c.service.Revoke(ctx.Request.Context(), aUuid); err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// createMintedAPIKeyResponse hands out the raw key, which must not end up in any cache.
+func createMintedAPIKeyResponse(ctx *gin.Context, minted *model.MintedAPIKey) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, dto.MintedAPIKeyResponse{
+		APIKeyResponse: toAPIKeyResponse(&minted.APIKey),
+		Key:            minted.RawKey,
+	})
+}
+
+func toAPIKeyResponse(key *model.APIKey) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		UUID:       key.UUID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		Roles:      key.Roles,
+		CreatedAt:  key.CreatedAt.UTC(),
+		ExpiresAt:  toOptionalTime(key.ExpiresAt),
+		LastUsedAt: toOptionalTime(key.LastUsedAt),
+		RevokedAt:  toOptionalTime(key.RevokedAt),
+	}
+}
+
+func toOptionalTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	utc := value.Time.UTC()
+	return &utc
+}
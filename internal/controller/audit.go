@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"cruder/internal/controller/dto"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func auditContext(ctx *gin.Context) model.AuditContext {
+	return model.AuditContext{
+		Actor:     ctx.GetString(middleware.ActorKey),
+		RequestID: ctx.GetString(middleware.RequestIDKey),
+	}
+}
+
+func toAuditPageResponse(page *model.AuditPage) dto.AuditPageResponse {
+	entries := make([]dto.AuditEntryResponse, 0, len(page.Entries))
+	for _, entry := range page.Entries {
+		changes := make(map[string]dto.AuditFieldChange, len(entry.Changes))
+		for field, change := range entry.Changes {
+			changes[field] = dto.AuditFieldChange{Before: change.Before, After: change.After}
+		}
+
+		entries = append(entries, dto.AuditEntryResponse{
+			Action:    entry.Action,
+			Actor:     entry.Actor,
+			RequestID: entry.RequestID,
+			Changes:   changes,
+			CreatedAt: entry.CreatedAt.UTC(),
+		})
+	}
+
+	return dto.AuditPageResponse{
+		Data:       entries,
+		NextCursor: toOptionalCursor(page.NextCursor),
+		PrevCursor: toOptionalCursor(page.PrevCursor),
+	}
+}
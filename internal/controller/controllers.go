@@ -1,13 +1,18 @@
 package controller
 
-import "cruder/internal/service"
+import (
+	"cruder/internal/config"
+	"cruder/internal/service"
+)
 
 type Controller struct {
-	Users *UserController
+	Users   *UserController
+	APIKeys *APIKeyController
 }
 
-func NewController(services *service.Service) *Controller {
+func NewController(services *service.Service, appConfig config.Config) *Controller {
 	return &Controller{
-		Users: NewUserController(services.Users),
+		Users:   NewUserController(services.Users, appConfig),
+		APIKeys: NewAPIKeyController(services.APIKeys),
 	}
 }
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+)
+
+type AuditFieldChange struct {
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+type AuditEntryResponse struct {
+	Action    string                      `json:"action"`
+	Actor     string                      `json:"actor"`
+	RequestID string                      `json:"request_id"`
+	Changes   map[string]AuditFieldChange `json:"changes"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+type AuditPageResponse struct {
+	Data       []AuditEntryResponse `json:"data"`
+	NextCursor *string              `json:"next_cursor"`
+	PrevCursor *string              `json:"prev_cursor"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"cruder/internal/problem"
+	"github.com/google/uuid"
+)
+
+type UserBatchRequest struct {
+	Operations []UserBatchOperation `json:"operations"`
+}
+
+type UserBatchOperation struct {
+	Op      string      `json:"op"`
+	UUID    *uuid.UUID  `json:"uuid"`
+	Version *int        `json:"version"`
+	User    *UserCreate `json:"user"`
+	Patch   *UserPatch  `json:"patch"`
+}
+
+type UserBatchResult struct {
+	Status int              `json:"status"`
+	User   *UserResponse    `json:"user,omitempty"`
+	Error  *problem.Details `json:"error,omitempty"`
+}
+
+type UserBatchResponse struct {
+	Results []UserBatchResult `json:"results"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "encoding/json"
+
+// UserMergePatch is an RFC 7396 merge patch of a user, a null member clears the field.
+type UserMergePatch map[string]json.RawMessage
+
+// JSONPatchOperation is a single RFC 6902 operation, Value holds the JSON null if given as such.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
@@ -2,13 +2,34 @@ package dto
 
 import (
 	"github.com/google/uuid"
+	"time"
 )
 
 type UserResponse struct {
-	UUID     uuid.UUID `json:"uuid"`
-	Username string    `json:"username"`
-	Email    string    `json:"email"`
-	FullName *string   `json:"full_name"`
+	UUID      uuid.UUID  `json:"uuid"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	FullName  *string    `json:"full_name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type UserPageResponse struct {
+	Data       []UserResponse `json:"data"`
+	NextCursor *string        `json:"next_cursor"`
+	PrevCursor *string        `json:"prev_cursor"`
+}
+
+type UserSearchResultResponse struct {
+	UserResponse
+	Score float64 `json:"score"`
+}
+
+type UserSearchPageResponse struct {
+	Data       []UserSearchResultResponse `json:"data"`
+	NextCursor *string                    `json:"next_cursor"`
+	PrevCursor *string                    `json:"prev_cursor"`
 }
 
 type UserPatch struct {
@@ -17,6 +38,11 @@ type UserPatch struct {
 	FullName *ErasableString `json:"full_name"`
 }
 
+type UserUpsert struct {
+	Email    string  `json:"email"`
+	FullName *string `json:"full_name"`
+}
+
 type UserCreate struct {
 	Username string  `json:"username"`
 	Email    string  `json:"email"`
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"cruder/pkg/validation"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const genericServerErrorValue = "It's not you. It's us. We are already working on it."
+const timeoutServerErrorValue = "the request took too long to process"
+const validationClientErrorValue = "validation failed"
+
+var statusByErrorKind = map[model.ErrorKind]int{
+	model.ErrorKindNotFound:           http.StatusNotFound,
+	model.ErrorKindConflict:           http.StatusConflict,
+	model.ErrorKindPreconditionFailed: http.StatusPreconditionFailed,
+	model.ErrorKindInvalidInput:       http.StatusBadRequest,
+}
+
+// createErrorResponse maps a failure of a service call to a problem details response.
+func createErrorResponse(err error, ctx *gin.Context) {
+	problem.Abort(ctx, toProblem(err, ctx))
+}
+
+func toProblem(err error, ctx *gin.Context) problem.Details {
+	var batchErr *model.BatchOperationError
+	var validationErrors validation.Errors
+	var businessErr *model.BusinessError
+	var accessDeniedErr *model.AccessDeniedError
+
+	switch {
+	case errors.As(err, &batchErr):
+		details := toProblem(batchErr.Err, ctx)
+		details.Detail = fmt.Sprintf("operation %d: %s", batchErr.Index, details.Detail)
+		return details
+	case errors.As(err, &validationErrors):
+		fieldErrors := make([]problem.FieldError, 0, len(validationErrors))
+		for _, fieldError := range validationErrors {
+			fieldErrors = append(fieldErrors, problem.FieldError{Field: fieldError.Field, Message: fieldError.Message})
+		}
+		return problem.New(http.StatusUnprocessableEntity, validationClientErrorValue).WithErrors(fieldErrors)
+	case errors.As(err, &accessDeniedErr):
+		fieldErrors := make([]problem.FieldError, 0, len(accessDeniedErr.Fields))
+		for _, field := range accessDeniedErr.Fields {
+			fieldErrors = append(fieldErrors, problem.FieldError{Field: field, Message: "is not allowed"})
+		}
+		return problem.New(http.StatusForbidden, accessDeniedErr.Error()).WithErrors(fieldErrors)
+	case errors.As(err, &businessErr) && statusByErrorKind[businessErr.Kind] != 0:
+		return problem.New(statusByErrorKind[businessErr.Kind], businessErr.Message)
+	// lib/pq reports a cancelled query as a server error, so the request context is checked as well.
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Request.Context().Err(), context.DeadlineExceeded):
+		return problem.New(http.StatusGatewayTimeout, timeoutServerErrorValue)
+	default:
+		slog.Error("request failed", "path", ctx.FullPath(), "actor", ctx.GetString(middleware.ActorKey), "error", err)
+		return problem.New(http.StatusInternalServerError, genericServerErrorValue)
+	}
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var userListQueryParameters = map[string]bool{
+	"limit":              true,
+	"cursor":             true,
+	"sort":               true,
+	"username_prefix":    true,
+	"email_domain":       true,
+	"full_name_contains": true,
+	"created_after":      true,
+	"created_before":     true,
+}
+
+func parseUserFilter(ctx *gin.Context) (model.UserFilter, error) {
+	var filter model.UserFilter
+
+	query := ctx.Request.URL.Query()
+	for parameter, values := range query {
+		if !userListQueryParameters[parameter] {
+			return filter, fmt.Errorf("unknown query parameter %s", parameter)
+		}
+		if len(values) != 1 {
+			return filter, fmt.Errorf("query parameter %s must be given once", parameter)
+		}
+	}
+
+	var err error
+	if filter.UsernamePrefix, err = parseTextFilter(query, "username_prefix"); err != nil {
+		return filter, err
+	}
+	if filter.EmailDomain, err = parseTextFilter(query, "email_domain"); err != nil {
+		return filter, err
+	}
+	if filter.EmailDomain != nil && strings.Contains(*filter.EmailDomain, "@") {
+		return filter, errors.New("invalid email_domain")
+	}
+	if filter.FullNameContains, err = parseTextFilter(query, "full_name_contains"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedAfter, err = parseTimeFilter(query, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTimeFilter(query, "created_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseTextFilter(query url.Values, parameter string) (*string, error) {
+	if !query.Has(parameter) {
+		return nil, nil
+	}
+
+	value := query.Get(parameter)
+	if value == "" {
+		return nil, fmt.Errorf("query parameter %s must not be empty", parameter)
+	}
+
+	return &value, nil
+}
+
+func parseTimeFilter(query url.Values, parameter string) (*time.Time, error) {
+	if !query.Has(parameter) {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, query.Get(parameter))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, RFC 3339 timestamp expected", parameter)
+	}
+	parsed = parsed.UTC()
+
+	return &parsed, nil
+}
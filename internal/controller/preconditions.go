@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ifMatchRequiredClientErrorValue = "the If-Match header is required"
+
+func setETag(ctx *gin.Context, user *model.User) {
+	ctx.Header("ETag", strconv.Quote(strconv.Itoa(user.Version)))
+}
+
+// parseIfMatch returns the user versions the If-Match header accepts. Nil stands for
+// any version, which is also the case for "*". Weak or foreign tags never match.
+func parseIfMatch(ctx *gin.Context) (versions []int, present bool) {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return nil, false
+	}
+
+	versions = []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil || !strings.HasPrefix(tag, `"`) {
+			continue
+		}
+		if version, err := strconv.Atoi(unquoted); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, true
+}
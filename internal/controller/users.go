@@ -1,13 +1,20 @@
 package controller
 
 import (
+	"context"
+	"cruder/internal/config"
 	"cruder/internal/controller/dto"
+	"cruder/internal/middleware"
 	"cruder/internal/model"
-	"cruder/internal/repository"
+	"cruder/internal/problem"
 	"errors"
 	"github.com/google/uuid"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"cruder/internal/service"
 
@@ -15,136 +22,456 @@ import (
 )
 
 type UserController struct {
-	service service.UserService
+	service         service.UserService
+	ifMatchRequired bool
 }
 
-const errorKey = "error"
-const genericServerErrorValue = "It's not you. It's us. We are already working on it."
 const invalidIdClientErrorValue = "invalid id"
 const invalidUuidIdClientErrorValue = "invalid UUID"
+const invalidLimitClientErrorValue = "invalid limit"
+const invalidSortClientErrorValue = "invalid sort"
+const invalidRequestBodyClientErrorValue = "invalid request body"
+const invalidSearchQueryClientErrorValue = "query parameter q must contain between 1 and 100 characters"
 
-func NewUserController(service service.UserService) *UserController {
-	return &UserController{service: service}
+const invalidAtomicClientErrorValue = "invalid atomic"
+const invalidBatchSizeClientErrorValue = "a batch must contain between 1 and 1000 operations"
+const missingDeleteScopeClientErrorValue = "missing scope " + model.ScopeUsersDelete
+
+const mergePatchContentType = "application/merge-patch+json"
+const jsonPatchContentType = "application/json-patch+json"
+
+const defaultPageLimit = 20
+const maxPageLimit = 100
+const maxBatchOperations = 1000
+const maxSearchQueryLength = 100
+
+var statusByOperationKind = map[string]int{
+	model.UserOperationCreate: http.StatusCreated,
+	model.UserOperationPatch:  http.StatusOK,
+	model.UserOperationDelete: http.StatusNoContent,
+}
+
+func NewUserController(service service.UserService, appConfig config.Config) *UserController {
+	return &UserController{
+		service:         service,
+		ifMatchRequired: appConfig.IfMatchRequired,
+	}
 }
 
 func (c *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := c.service.GetAll()
+	c.listUsers(ctx, c.service.GetAll)
+}
+
+func (c *UserController) GetAllDeletedUsers(ctx *gin.Context) {
+	c.listUsers(ctx, c.service.GetAllDeleted)
+}
+
+func (c *UserController) listUsers(
+	ctx *gin.Context,
+	list func(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error),
+) {
+	filter, err := parseUserFilter(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pageRequest, err := parsePageRequest(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := list(ctx.Request.Context(), filter, pageRequest)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toUserPageResponse(page))
+}
+
+func (c *UserController) SearchUsers(ctx *gin.Context) {
+	text := strings.TrimSpace(ctx.Query("q"))
+	if text == "" || utf8.RuneCountInString(text) > maxSearchQueryLength {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidSearchQueryClientErrorValue)
+		return
+	}
+
+	pageRequest, err := parsePageRequest(ctx)
+	if err == nil && pageRequest.Sort != nil {
+		err = errors.New(invalidSortClientErrorValue)
+	}
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := c.service.Search(ctx.Request.Context(), text, pageRequest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{errorKey: genericServerErrorValue})
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, toUserResponses(users))
+	ctx.JSON(http.StatusOK, toUserSearchPageResponse(page))
 }
 
 func (c *UserController) GetUserByUsername(ctx *gin.Context) {
 	username := ctx.Param("username")
 
-	user, err := c.service.GetByUsername(username)
-	createSingleUserResponse(user, err, ctx)
+	user, err := c.service.GetByUsername(ctx.Request.Context(), username)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	createUserResponse(ctx, http.StatusOK, user)
+}
+
+func (c *UserController) GetUserByEmail(ctx *gin.Context) {
+	email := ctx.Param("email")
+
+	user, err := c.service.GetByEmail(ctx.Request.Context(), email)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	createUserResponse(ctx, http.StatusOK, user)
 }
 
 func (c *UserController) GetUserByID(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{errorKey: invalidIdClientErrorValue})
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidIdClientErrorValue)
+		return
+	}
+
+	user, err := c.service.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	user, err := c.service.GetByID(id)
-	createSingleUserResponse(user, err, ctx)
+	createUserResponse(ctx, http.StatusOK, user)
+}
+
+func (c *UserController) GetUserByUuid(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	user, err := c.service.GetByUuid(ctx.Request.Context(), aUuid)
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	createUserResponse(ctx, http.StatusOK, user)
 }
 
 func (c *UserController) DeleteUserByUuid(ctx *gin.Context) {
 	aUuid, err := parseUuid(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{errorKey: invalidUuidIdClientErrorValue})
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
 		return
 	}
 
-	err = c.service.DeleteByUuid(aUuid)
-	createNoContentResponse(err, ctx)
+	expectedVersions, present := parseIfMatch(ctx)
+	if !present && c.ifMatchRequired {
+		problem.AbortWithStatus(ctx, http.StatusPreconditionRequired, ifMatchRequiredClientErrorValue)
+		return
+	}
+
+	err = c.service.DeleteByUuid(ctx.Request.Context(), aUuid, expectedVersions, auditContext(ctx))
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
 }
 
-func (c *UserController) PatchUserByUuid(ctx *gin.Context) {
+func (c *UserController) RestoreUserByUuid(ctx *gin.Context) {
 	aUuid, err := parseUuid(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{errorKey: invalidUuidIdClientErrorValue})
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
 		return
 	}
 
-	var patch dto.UserPatch
-	if err := ctx.ShouldBindJSON(&patch); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	user, err := c.service.RestoreByUuid(ctx.Request.Context(), aUuid, auditContext(ctx))
+	if err != nil {
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	err = c.service.PartiallyUpdateByUuid(aUuid, patch)
-	createNoContentResponse(err, ctx)
+	createUserResponse(ctx, http.StatusOK, user)
 }
 
-func (c *UserController) CreateUser(ctx *gin.Context) {
-	var user dto.UserCreate
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+func (c *UserController) GetUserHistoryByUuid(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	pageRequest, err := parsePageRequest(ctx)
+	if err == nil && pageRequest.Sort != nil {
+		err = errors.New(invalidSortClientErrorValue)
+	}
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := c.service.GetHistoryByUuid(ctx.Request.Context(), aUuid, pageRequest)
+	if err != nil {
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	createdUser, err := c.service.Create(user)
-	createCreatedResponse(createdUser, err, ctx)
+	ctx.JSON(http.StatusOK, toAuditPageResponse(page))
 }
 
-func parseUuid(ctx *gin.Context) (uuid.UUID, error) {
-	uuidStr := ctx.Param("uuid")
-	return uuid.Parse(uuidStr)
+func (c *UserController) PatchUserByUuid(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	expectedVersions, present := parseIfMatch(ctx)
+	if !present && c.ifMatchRequired {
+		problem.AbortWithStatus(ctx, http.StatusPreconditionRequired, ifMatchRequiredClientErrorValue)
+		return
+	}
+
+	var user *model.User
+	switch ctx.ContentType() {
+	case mergePatchContentType:
+		var patch dto.UserMergePatch
+		if err := ctx.ShouldBindJSON(&patch); err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+			return
+		}
+		user, err = c.service.MergePatchByUuid(ctx.Request.Context(), aUuid, patch, expectedVersions, auditContext(ctx))
+	case jsonPatchContentType:
+		var operations []dto.JSONPatchOperation
+		if err := ctx.ShouldBindJSON(&operations); err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+			return
+		}
+		user, err = c.service.JSONPatchByUuid(ctx.Request.Context(), aUuid, operations, expectedVersions, auditContext(ctx))
+	default:
+		var patch dto.UserPatch
+		if err := ctx.ShouldBindJSON(&patch); err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+			return
+		}
+		user, err = c.service.PartiallyUpdateByUuid(ctx.Request.Context(), aUuid, patch, expectedVersions, auditContext(ctx))
+	}
+	if err != nil {
+		createErrorResponse(err, ctx)
+		return
+	}
+
+	setETag(ctx, user)
+	ctx.Status(http.StatusNoContent)
 }
 
-func createSingleUserResponse(user *model.User, err error, ctx *gin.Context) {
-	if errors.Is(err, repository.BusinessErrNoUsers) {
-		ctx.JSON(http.StatusNotFound, gin.H{errorKey: err.Error()})
+func (c *UserController) PutUserByUuid(ctx *gin.Context) {
+	aUuid, err := parseUuid(ctx)
+	if err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidUuidIdClientErrorValue)
+		return
+	}
+
+	expectedVersions, present := parseIfMatch(ctx)
+	if !present && c.ifMatchRequired {
+		problem.AbortWithStatus(ctx, http.StatusPreconditionRequired, ifMatchRequiredClientErrorValue)
+		return
+	}
+
+	var user dto.UserCreate
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
 		return
 	}
+
+	replacedUser, err := c.service.ReplaceByUuid(ctx.Request.Context(), aUuid, user, expectedVersions, auditContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{errorKey: genericServerErrorValue})
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, toUserResponse(user))
+	setETag(ctx, replacedUser)
+	ctx.Status(http.StatusNoContent)
 }
 
-func createNoContentResponse(err error, ctx *gin.Context) {
-	if errors.Is(err, repository.BusinessErrNoUsers) {
-		ctx.JSON(http.StatusNotFound, gin.H{errorKey: err.Error()})
+func (c *UserController) CreateUser(ctx *gin.Context) {
+	var user dto.UserCreate
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
 		return
 	}
-	if errors.Is(err, repository.BusinessErrUsernameTaken) ||
-		errors.Is(err, repository.BusinessErrEmailTaken) ||
-		errors.Is(err, repository.BusinessErrUnknownConflict) {
-		ctx.JSON(http.StatusConflict, gin.H{errorKey: err.Error()})
+
+	createdUser, err := c.service.Create(ctx.Request.Context(), user, auditContext(ctx))
+	if err != nil {
+		createErrorResponse(err, ctx)
 		return
 	}
+
+	createUserResponse(ctx, http.StatusCreated, createdUser)
+}
+
+func (c *UserController) PutUserByUsername(ctx *gin.Context) {
+	username := ctx.Param("username")
+
+	var user dto.UserUpsert
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+		return
+	}
+
+	upsertedUser, created, err := c.service.UpsertByUsername(ctx.Request.Context(), username, user, auditContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{errorKey: genericServerErrorValue})
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	ctx.JSON(http.StatusNoContent, nil)
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	createUserResponse(ctx, status, upsertedUser)
 }
 
-func createCreatedResponse(user *model.User, err error, ctx *gin.Context) {
-	if errors.Is(err, repository.BusinessErrUsernameTaken) ||
-		errors.Is(err, repository.BusinessErrEmailTaken) ||
-		errors.Is(err, repository.BusinessErrUnknownConflict) {
-		ctx.JSON(http.StatusConflict, gin.H{errorKey: err.Error()})
+func (c *UserController) BatchUsers(ctx *gin.Context) {
+	atomic := true
+	if atomicStr, present := ctx.GetQuery("atomic"); present {
+		var err error
+		if atomic, err = strconv.ParseBool(atomicStr); err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidAtomicClientErrorValue)
+			return
+		}
+	}
+
+	var batch dto.UserBatchRequest
+	if err := ctx.ShouldBindJSON(&batch); err != nil {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidRequestBodyClientErrorValue)
+		return
+	}
+	if len(batch.Operations) == 0 || len(batch.Operations) > maxBatchOperations {
+		problem.AbortWithStatus(ctx, http.StatusBadRequest, invalidBatchSizeClientErrorValue)
 		return
 	}
+	if !middleware.GetPrincipal(ctx).HasScope(model.ScopeUsersDelete) && containsDeletion(batch.Operations) {
+		problem.AbortWithStatus(ctx, http.StatusForbidden, missingDeleteScopeClientErrorValue)
+		return
+	}
+
+	results, err := c.service.ExecuteBatch(ctx.Request.Context(), batch.Operations, atomic, auditContext(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{errorKey: genericServerErrorValue})
+		createErrorResponse(err, ctx)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, toUserResponse(user))
+	ctx.JSON(http.StatusOK, toUserBatchResponse(results, ctx))
+}
+
+func containsDeletion(operations []dto.UserBatchOperation) bool {
+	return slices.ContainsFunc(operations, func(operation dto.UserBatchOperation) bool {
+		return operation.Op == model.UserOperationDelete
+	})
+}
+
+func parseUuid(ctx *gin.Context) (uuid.UUID, error) {
+	uuidStr := ctx.Param("uuid")
+	return uuid.Parse(uuidStr)
+}
+
+func parsePageRequest(ctx *gin.Context) (model.PageRequest, error) {
+	pageRequest := model.PageRequest{
+		Limit:  defaultPageLimit,
+		Cursor: ctx.Query("cursor"),
+	}
+
+	if limitStr, present := ctx.GetQuery("limit"); present {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return pageRequest, errors.New(invalidLimitClientErrorValue)
+		}
+		pageRequest.Limit = limit
+	}
+
+	if sortStr, present := ctx.GetQuery("sort"); present {
+		for _, fieldStr := range strings.Split(sortStr, ",") {
+			field := model.SortField{Field: strings.TrimPrefix(fieldStr, "-")}
+			field.Descending = field.Field != fieldStr
+			if field.Field == "" {
+				return pageRequest, errors.New(invalidSortClientErrorValue)
+			}
+			pageRequest.Sort = append(pageRequest.Sort, field)
+		}
+	}
+
+	return pageRequest, nil
+}
+
+func createUserResponse(ctx *gin.Context, status int, user *model.User) {
+	setETag(ctx, user)
+	ctx.JSON(status, toUserResponse(user))
+}
+
+func toUserBatchResponse(results []model.UserOperationResult, ctx *gin.Context) dto.UserBatchResponse {
+	response := dto.UserBatchResponse{Results: make([]dto.UserBatchResult, 0, len(results))}
+	for _, result := range results {
+		if result.Err != nil {
+			details := toProblem(result.Err, ctx)
+			response.Results = append(response.Results, dto.UserBatchResult{Status: details.Status, Error: &details})
+			continue
+		}
+
+		batchResult := dto.UserBatchResult{Status: statusByOperationKind[result.Kind]}
+		if result.User != nil {
+			user := toUserResponse(result.User)
+			batchResult.User = &user
+		}
+		response.Results = append(response.Results, batchResult)
+	}
+
+	return response
+}
+
+func toUserPageResponse(page *model.UserPage) dto.UserPageResponse {
+	return dto.UserPageResponse{
+		Data:       toUserResponses(page.Users),
+		NextCursor: toOptionalCursor(page.NextCursor),
+		PrevCursor: toOptionalCursor(page.PrevCursor),
+	}
+}
+
+func toUserSearchPageResponse(page *model.UserSearchPage) dto.UserSearchPageResponse {
+	results := make([]dto.UserSearchResultResponse, 0, len(page.Results))
+	for _, result := range page.Results {
+		results = append(results, dto.UserSearchResultResponse{UserResponse: toUserResponse(&result.User), Score: result.Score})
+	}
+
+	return dto.UserSearchPageResponse{
+		Data:       results,
+		NextCursor: toOptionalCursor(page.NextCursor),
+		PrevCursor: toOptionalCursor(page.PrevCursor),
+	}
+}
+
+func toOptionalCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
 }
 
 func toUserResponses(users []model.User) []dto.UserResponse {
@@ -161,10 +488,19 @@ func toUserResponse(user *model.User) dto.UserResponse {
 		fullName = &user.FullName.String
 	}
 
+	var deletedAt *time.Time
+	if user.DeletedAt.Valid {
+		deletedAtUTC := user.DeletedAt.Time.UTC()
+		deletedAt = &deletedAtUTC
+	}
+
 	return dto.UserResponse{
-		UUID:     user.UUID,
-		Username: user.Username,
-		Email:    user.Email,
-		FullName: fullName,
+		UUID:      user.UUID,
+		Username:  user.Username,
+		Email:     user.Email,
+		FullName:  fullName,
+		CreatedAt: user.CreatedAt.UTC(),
+		UpdatedAt: user.UpdatedAt.UTC(),
+		DeletedAt: deletedAt,
 	}
 }
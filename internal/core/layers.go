@@ -1,6 +1,7 @@
 package core
 
 import (
+	"cruder/internal/config"
 	"cruder/internal/controller"
 	"cruder/internal/handler"
 	"cruder/internal/repository"
@@ -9,12 +10,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAppLayers(db *sql.DB, xApiKey string) (*repository.Repository, *gin.Engine) {
+func SetupAppLayers(db *sql.DB, appConfig config.Config) (*repository.Repository, *gin.Engine) {
 	repositories := repository.NewRepository(db)
-	services := service.NewService(repositories)
-	controllers := controller.NewController(services)
+	services := service.NewService(repositories, appConfig.UserPolicy)
+	controllers := controller.NewController(services, appConfig)
 	httpRouterEngine := gin.Default()
-	handler.New(httpRouterEngine, controllers.Users, xApiKey)
+	handler.New(httpRouterEngine, controllers, repositories.IdempotencyKeys, services.APIKeys, appConfig)
 
 	return repositories, httpRouterEngine
 }
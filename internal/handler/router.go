@@ -1,29 +1,105 @@
 package handler
 
 import (
+	"cruder/internal/config"
 	"cruder/internal/controller"
 	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"cruder/internal/repository"
+	"cruder/pkg/jwt"
 	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
 )
 
 func New(
 	router *gin.Engine,
-	userController *controller.UserController,
-	apiKey string) *gin.Engine {
-	apiV1Group := router.Group("/api/v1", middleware.APIKeyAuth(apiKey))
+	controllers *controller.Controller,
+	idempotencyKeys repository.IdempotencyKeyRepository,
+	apiKeys middleware.APIKeyAuthenticator,
+	appConfig config.Config) *gin.Engine {
+	userController := controllers.Users
+	apiKeyController := controllers.APIKeys
+	idempotency := middleware.Idempotency(idempotencyKeys, appConfig.IdempotencyKeyTTL, appConfig.IdempotencyLease)
+	read := middleware.RequireScope(model.ScopeUsersRead)
+	write := middleware.RequireScope(model.ScopeUsersWrite)
+	remove := middleware.RequireScope(model.ScopeUsersDelete)
+	usersAdmin := middleware.RequireScope(model.ScopeUsersAdmin)
+	apiKeysAdmin := middleware.RequireScope(model.ScopeAPIKeysAdmin)
+
+	customMethods := map[string]gin.HandlerFunc{
+		"batch": userController.BatchUsers,
+	}
+
+	authentication := middleware.APIKeyAuth(
+		appConfig.XApiKey, appConfig.APIKeys, apiKeys, appConfig.APIKeyCacheTTL, appConfig.JWKSSource == "")
+	if appConfig.JWKSSource != "" {
+		keys := jwt.NewKeySource(appConfig.JWKSSource, appConfig.JWKSRefreshInterval)
+		verifier := jwt.NewVerifier(keys, appConfig.JWTIssuer, appConfig.JWTAudience)
+		authentication = middleware.BearerAuth(verifier, appConfig.JWTScopeMapping, authentication)
+	}
+	if len(appConfig.APIKeys) > 0 {
+		authentication = middleware.SignatureAuth(appConfig.APIKeys, appConfig.SignatureWindow, authentication)
+	}
+
+	apiV1Group := router.Group(
+		"/api/v1",
+		middleware.RequestID(),
+		// ahead of the authentication, which may look up the stored keys or fetch the JWKS
+		middleware.Timeout(appConfig.DBTimeout),
+		authentication)
 	{
+		// gin routes an escaped colon only once the engine is run, so the custom methods
+		// like /users:batch are matched by a wildcard right after /users instead
+		// a batch with deletions is also checked for the delete scope by the controller
+		apiV1Group.POST("/users:method", write, idempotency, customMethod(customMethods))
+
 		userGroup := apiV1Group.Group("/users")
 		{
-			userGroup.GET("/", userController.GetAllUsers)
-			userGroup.GET("", userController.GetAllUsers)
-			userGroup.GET("/username/:username", userController.GetUserByUsername)
-			userGroup.GET("/id/:id", userController.GetUserByID) //This should never exist, to be honest. We are not even going to test it.
-			userGroup.DELETE("/:uuid", userController.DeleteUserByUuid)
-			userGroup.PATCH("/:uuid", userController.PatchUserByUuid)
-			userGroup.POST("", userController.CreateUser)
-			userGroup.POST("/", userController.CreateUser)
+			userGroup.GET("/", read, userController.GetAllUsers)
+			userGroup.GET("", read, userController.GetAllUsers)
+			userGroup.GET("/deleted", usersAdmin, userController.GetAllDeletedUsers)
+			userGroup.GET("/search", read, userController.SearchUsers)
+			userGroup.GET("/username/:username", read, userController.GetUserByUsername)
+			userGroup.PUT("/username/:username", write, userController.PutUserByUsername)
+			userGroup.GET("/email/:email", read, userController.GetUserByEmail)
+			if !appConfig.IdRouteDisabled {
+				//This should never exist, to be honest. We are not even going to test it. Use /:uuid instead.
+				userGroup.GET("/id/:id", read, middleware.Deprecated(), userController.GetUserByID)
+			}
+			userGroup.GET("/:uuid", read, userController.GetUserByUuid)
+			userGroup.DELETE("/:uuid", remove, userController.DeleteUserByUuid)
+			userGroup.POST("/:uuid/restore", usersAdmin, userController.RestoreUserByUuid)
+			userGroup.GET("/:uuid/history", read, userController.GetUserHistoryByUuid)
+			userGroup.PATCH("/:uuid", write, userController.PatchUserByUuid)
+			userGroup.PUT("/:uuid", write, userController.PutUserByUuid)
+			userGroup.POST("", write, idempotency, userController.CreateUser)
+			userGroup.POST("/", write, idempotency, userController.CreateUser)
+		}
+
+		// no idempotency here, it would store the raw keys of the responses
+		apiKeyGroup := apiV1Group.Group("/api-keys", apiKeysAdmin)
+		{
+			apiKeyGroup.GET("", apiKeyController.GetAllAPIKeys)
+			apiKeyGroup.POST("", apiKeyController.MintAPIKey)
+			apiKeyGroup.POST("/:uuid/rotate", apiKeyController.RotateAPIKey)
+			apiKeyGroup.DELETE("/:uuid", apiKeyController.RevokeAPIKey)
 		}
 	}
 
 	return router
 }
+
+func customMethod(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		method, found := strings.CutPrefix(ctx.Param("method"), ":")
+		handler := handlers[method]
+		if !found || handler == nil {
+			problem.AbortWithStatus(ctx, http.StatusNotFound, "unknown method")
+			return
+		}
+
+		handler(ctx)
+	}
+}
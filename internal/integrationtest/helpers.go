@@ -2,6 +2,7 @@ package integrationtest
 
 import (
 	"context"
+	"cruder/internal/model"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -15,6 +16,8 @@ import (
 	"time"
 )
 
+var testAuditContext = model.AuditContext{Actor: "integration-test", RequestID: "integration-test"}
+
 func prepareDbWithTestData(t *testing.T) (*sql.DB, uuid.UUID, uuid.UUID) {
 	t.Helper()
 
@@ -115,6 +118,23 @@ func insertTestData(t *testing.T, db *sql.DB) (uuid.UUID, uuid.UUID) {
 	return uuidHarry, uuidKim
 }
 
+type userPage struct {
+	Data       []map[string]interface{} `json:"data"`
+	NextCursor *string                  `json:"next_cursor"`
+	PrevCursor *string                  `json:"prev_cursor"`
+}
+
+func unmarshalUserPage(t *testing.T, responseRecorder *httptest.ResponseRecorder) userPage {
+	t.Helper()
+
+	var page userPage
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	return page
+}
+
 func assertThatResponseCodeIsExpected(t *testing.T, responseRecorder *httptest.ResponseRecorder, expectedCode int) {
 	t.Helper()
 
@@ -123,6 +143,14 @@ func assertThatResponseCodeIsExpected(t *testing.T, responseRecorder *httptest.R
 	}
 }
 
+func assertThatETagIsExpected(t *testing.T, responseRecorder *httptest.ResponseRecorder, expectedETag string) {
+	t.Helper()
+
+	if eTag := responseRecorder.Header().Get("ETag"); eTag != expectedETag {
+		t.Fatalf("expected ETag %s, got %s", expectedETag, eTag)
+	}
+}
+
 func assertThatUserFieldsAreExpected(
 	t *testing.T,
 	user map[string]interface{},
@@ -155,12 +183,16 @@ func assertThatUsernameAndEmailAreExpected(
 func assertThatErrorMessageIsExpected(t *testing.T, responseRecorder *httptest.ResponseRecorder, expectedMessage string) {
 	t.Helper()
 
-	var jsonError map[string]interface{}
-	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &jsonError); err != nil {
+	if contentType := responseRecorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+
+	var problem map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("invalid JSON: %v", err)
 	}
 
-	if jsonError["error"] != expectedMessage {
-		t.Fatalf("unexpected error message: %+v", jsonError)
+	if problem["detail"] != expectedMessage || problem["status"] != float64(responseRecorder.Code) {
+		t.Fatalf("unexpected problem details: %+v", problem)
 	}
 }
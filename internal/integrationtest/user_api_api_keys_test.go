@@ -0,0 +1,242 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bootstrapKey is the configured key minting the first stored ones, which anonymous callers may not do.
+const bootstrapKey = "Martinaise bootstrap key"
+
+var bootstrapConfig = config.Config{APIKeys: []config.APIKey{
+	{Name: "bootstrap", Key: bootstrapKey, Scopes: []string{model.ScopeAPIKeysAdmin}},
+}}
+
+type mintedAPIKey struct {
+	UUID       string   `json:"uuid"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	LastUsedAt *string  `json:"last_used_at"`
+	RevokedAt  *string  `json:"revoked_at"`
+	Key        string   `json:"key"`
+}
+
+func TestMintAPIKey_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+
+	minted := mintAPIKey(t, router, bootstrapKey, `{"name": "support", "scopes": ["users:read", "api-keys:admin"]}`)
+	if !strings.HasPrefix(minted.Key, minted.Prefix+"_") {
+		t.Fatalf("the key %s does not start with its prefix %s", minted.Key, minted.Prefix)
+	}
+
+	responseRecorder := sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", "")
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", minted.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/api-keys", minted.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	var list struct {
+		Data []mintedAPIKey `json:"data"`
+	}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Key != "" || list.Data[0].LastUsedAt == nil {
+		t.Fatalf("unexpected keys: %+v", list.Data)
+	}
+}
+
+func TestRevokedAPIKey_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	admin := mintAPIKey(t, router, bootstrapKey, `{"name": "admin", "scopes": ["api-keys:admin"]}`)
+	reader := mintAPIKey(t, router, admin.Key, `{"name": "reader", "scopes": ["users:read"]}`)
+
+	responseRecorder := sendWithAPIKey(router, http.MethodDelete, "/api/v1/api-keys/"+reader.UUID, admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", reader.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "Invalid API key")
+
+	responseRecorder = sendWithAPIKey(router, http.MethodDelete, "/api/v1/api-keys/"+reader.UUID, admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+}
+
+func TestRotateAPIKey_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	admin := mintAPIKey(t, router, bootstrapKey, `{"name": "admin", "scopes": ["api-keys:admin"]}`)
+	reader := mintAPIKey(t, router, admin.Key, `{"name": "reader", "scopes": ["users:read"]}`)
+
+	responseRecorder := sendWithAPIKey(router, http.MethodPost, "/api/v1/api-keys/"+reader.UUID+"/rotate", admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var rotated mintedAPIKey
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &rotated); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if rotated.UUID == reader.UUID || rotated.Key == reader.Key || len(rotated.Scopes) != 1 {
+		t.Fatalf("unexpected rotated key: %+v", rotated)
+	}
+
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", reader.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", rotated.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
+
+func TestRotateAPIKeyWithGracePeriod_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	admin := mintAPIKey(t, router, bootstrapKey, `{"name": "admin", "scopes": ["api-keys:admin", "users:read"]}`)
+
+	responseRecorder := sendWithAPIKey(
+		router, http.MethodPost, "/api/v1/api-keys/"+admin.UUID+"/rotate?grace_period=1h", admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+
+	responseRecorder = sendWithAPIKey(router, http.MethodGet, "/api/v1/users/username/kim", admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
+
+func TestMintAPIKeyWithUnknownScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/api-keys",
+		strings.NewReader(`{"name": "support", "scopes": ["users:everything"]}`))
+	req.Header.Set(apiHeaderKey, bootstrapKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "scopes")
+}
+
+func TestMintAPIKeyAnonymously_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/api-keys",
+		strings.NewReader(`{"name": "admin", "scopes": ["api-keys:admin"]}`))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope api-keys:admin")
+}
+
+func TestListAPIKeysWithoutAdminScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	reader := mintAPIKey(t, router, bootstrapKey, `{"name": "reader", "scopes": ["users:read"]}`)
+
+	responseRecorder := sendWithAPIKey(router, http.MethodGet, "/api/v1/api-keys", reader.Key)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope api-keys:admin")
+}
+
+func TestAPIKeysWithSameNameRecordDistinctActors_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	admin := mintAPIKey(t, router, bootstrapKey, `{"name": "admin", "scopes": ["users:read", "api-keys:admin"]}`)
+	first := mintAPIKey(t, router, admin.Key, `{"name": "ci", "scopes": ["users:write"]}`)
+	second := mintAPIKey(t, router, admin.Key, `{"name": "ci", "scopes": ["users:write"]}`)
+
+	for i, key := range []mintedAPIKey{first, second} {
+		body := fmt.Sprintf(`{"full_name": {"value": "Harrier Du Bois %d"}}`, i)
+		req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+		req.Header.Set(apiHeaderKey, key.Key)
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, req)
+		assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	}
+
+	responseRecorder := sendWithAPIKey(router, http.MethodGet, "/api/v1/users/"+uuidHarry.String()+"/history", admin.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	actors := map[string]bool{}
+	for _, entry := range unmarshalAuditPage(t, responseRecorder).Data {
+		actors[entry.Actor] = true
+	}
+	if !actors["api-key:ci:"+first.Prefix] || !actors["api-key:ci:"+second.Prefix] {
+		t.Fatalf("unexpected actors: %v", actors)
+	}
+}
+
+func TestCreateUserWithLongestAPIKeyName_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, bootstrapConfig)
+	name := strings.Repeat("n", 100)
+	writer := mintAPIKey(t, router, bootstrapKey, `{"name": "`+name+`", "scopes": ["users:read", "users:write"]}`)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, writer.Key)
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var createdUser map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &createdUser); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	responseRecorder = sendWithAPIKey(
+		router, http.MethodGet, "/api/v1/users/"+createdUser["uuid"].(string)+"/history", writer.Key)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	entries := unmarshalAuditPage(t, responseRecorder).Data
+	if len(entries) != 1 || entries[0].Actor != "api-key:"+name+":"+writer.Prefix {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
+
+func mintAPIKey(t *testing.T, router *gin.Engine, adminKey string, body string) mintedAPIKey {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/api-keys", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, adminKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	if responseRecorder.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("a minted key must not be cached")
+	}
+	var minted mintedAPIKey
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &minted); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	return minted
+}
+
+func sendWithAPIKey(router *gin.Engine, method string, url string, apiKey string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, nil)
+	if apiKey != "" {
+		req.Header.Set(apiHeaderKey, apiKey)
+	}
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	return responseRecorder
+}
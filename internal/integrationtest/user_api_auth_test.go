@@ -1,6 +1,7 @@
 package integrationtest
 
 import (
+	"cruder/internal/config"
 	"cruder/internal/core"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
@@ -15,7 +16,7 @@ func TestGetUserByUsernameWithCorrectXApiKey_Success(t *testing.T) {
 	key := "Les Cles de Fort Boyard"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, key)
+	_, router := core.SetupAppLayers(db, config.Config{XApiKey: key})
 
 	req, _ := http.NewRequest(
 		http.MethodGet, "/api/v1/users/username/kim",
@@ -37,7 +38,7 @@ func TestGetUserByUsernameWithMissingXApiKey_Failure(t *testing.T) {
 	key := "Les Cles de Fort Boyard"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, key)
+	_, router := core.SetupAppLayers(db, config.Config{XApiKey: key})
 
 	req, _ := http.NewRequest(
 		http.MethodGet, "/api/v1/users/username/kim",
@@ -53,7 +54,7 @@ func TestGetUserByUsernameWithWrongXApiKey_Failure(t *testing.T) {
 	key := "Les Cles de Fort Boyard"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, key)
+	_, router := core.SetupAppLayers(db, config.Config{XApiKey: key})
 
 	req, _ := http.NewRequest(
 		http.MethodGet, "/api/v1/users/username/kim",
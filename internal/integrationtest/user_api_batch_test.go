@@ -0,0 +1,121 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type batchResponse struct {
+	Results []struct {
+		Status int                    `json:"status"`
+		User   map[string]interface{} `json:"user"`
+		Error  map[string]interface{} `json:"error"`
+	} `json:"results"`
+}
+
+func TestBatchUsersAtomically_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, uuidKim := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"operations": [
+		{"op": "create", "user": {"username": "klaasje", "email": "klaasje.amandou@noname.com"}},
+		{"op": "patch", "uuid": "` + uuidHarry.String() + `", "patch": {"email": "raphael.costeau@rcm.org"}},
+		{"op": "delete", "uuid": "` + uuidKim.String() + `", "version": 1}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	response := unmarshalBatchResponse(t, responseRecorder)
+	if len(response.Results) != 3 ||
+		response.Results[0].Status != http.StatusCreated || response.Results[0].User["username"] != "klaasje" ||
+		response.Results[1].Status != http.StatusOK || response.Results[1].User["email"] != "raphael.costeau@rcm.org" ||
+		response.Results[2].Status != http.StatusNoContent || response.Results[2].User != nil {
+		t.Fatalf("unexpected results: %+v", response)
+	}
+	if _, err := repositories.Users.GetByUuid(context.Background(), uuidKim); err == nil {
+		t.Fatalf("user %s must have been deleted", uuidKim)
+	}
+}
+
+func TestBatchUsersAtomicallyWithConflict_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"operations": [
+		{"op": "create", "user": {"username": "klaasje", "email": "klaasje.amandou@noname.com"}},
+		{"op": "patch", "uuid": "` + uuidHarry.String() + `", "patch": {"username": "kim"}}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch?atomic=true", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "operation 1: the username is already taken")
+	if _, err := repositories.Users.GetByUsername(context.Background(), "klaasje"); err == nil {
+		t.Fatalf("the creation must have been rolled back")
+	}
+}
+
+func TestBatchUsersNonAtomically_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"operations": [
+		{"op": "create", "user": {"username": "klaasje", "email": "klaasje.amandou@noname.com"}},
+		{"op": "patch", "uuid": "` + uuidHarry.String() + `", "patch": {"username": "kim"}},
+		{"op": "create", "user": {"username": "", "email": "cuno@noname.com"}},
+		{"op": "delete"}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch?atomic=false", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	response := unmarshalBatchResponse(t, responseRecorder)
+	if len(response.Results) != 4 ||
+		response.Results[0].Status != http.StatusCreated ||
+		response.Results[1].Status != http.StatusConflict ||
+		response.Results[1].Error["detail"] != "the username is already taken" ||
+		response.Results[2].Status != http.StatusUnprocessableEntity ||
+		response.Results[3].Status != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected results: %+v", response)
+	}
+	if _, err := repositories.Users.GetByUsername(context.Background(), "klaasje"); err != nil {
+		t.Fatalf("user klaasje must have been created")
+	}
+}
+
+func TestBatchUsersWithUnknownMethod_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:merge", strings.NewReader(`{}`))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+}
+
+func unmarshalBatchResponse(t *testing.T, responseRecorder *httptest.ResponseRecorder) batchResponse {
+	t.Helper()
+
+	var response batchResponse
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	return response
+}
@@ -0,0 +1,204 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://idp.rcm.org"
+const testAudience = "cruder"
+
+type testIdentityProvider struct {
+	key      *ecdsa.PrivateKey
+	jwksPath string
+}
+
+func TestGetUserByUsernameWithBearerToken_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	idp := newTestIdentityProvider(t)
+	_, router := core.SetupAppLayers(db, idp.config(nil))
+
+	token := idp.sign(t, validClaims("users:read"))
+	responseRecorder := sendWithBearerToken(router, http.MethodGet, "/api/v1/users/username/kim", "", token)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
+
+func TestCreateUserWithBearerTokenLackingScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	idp := newTestIdentityProvider(t)
+	_, router := core.SetupAppLayers(db, idp.config(nil))
+
+	token := idp.sign(t, validClaims("users:read"))
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	responseRecorder := sendWithBearerToken(router, http.MethodPost, "/api/v1/users", body, token)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:write")
+}
+
+func TestCreateUserWithMappedBearerTokenScope_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	idp := newTestIdentityProvider(t)
+	_, router := core.SetupAppLayers(db, idp.config(map[string][]string{"support": {"users:read", "users:write"}}))
+
+	token := idp.sign(t, validClaims("support"))
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	responseRecorder := sendWithBearerToken(router, http.MethodPost, "/api/v1/users", body, token)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var createdUser map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &createdUser); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	responseRecorder = sendWithBearerToken(
+		router, http.MethodGet, "/api/v1/users/"+createdUser["uuid"].(string)+"/history", "", token)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	entries := unmarshalAuditPage(t, responseRecorder).Data
+	if len(entries) != 1 || entries[0].Actor != "jwt:kim.kitsuragi" {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
+
+func TestGetUserByUsernameWithInvalidBearerTokens_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	idp := newTestIdentityProvider(t)
+	otherIdp := newTestIdentityProvider(t)
+	_, router := core.SetupAppLayers(db, idp.config(nil))
+
+	expired := validClaims("users:read")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	notYetValid := validClaims("users:read")
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+	wrongAudience := validClaims("users:read")
+	wrongAudience["aud"] = []string{"another-service"}
+	wrongIssuer := validClaims("users:read")
+	wrongIssuer["iss"] = "https://idp.noname.com"
+	overlongSubject := validClaims("users:read")
+	overlongSubject["sub"] = strings.Repeat("s", 252)
+
+	tokens := map[string]string{
+		"expired":         idp.sign(t, expired),
+		"not valid yet":   idp.sign(t, notYetValid),
+		"wrong audience":  idp.sign(t, wrongAudience),
+		"wrong issuer":    idp.sign(t, wrongIssuer),
+		"overlong sub":    idp.sign(t, overlongSubject),
+		"foreign key":     otherIdp.sign(t, validClaims("users:read")),
+		"unsigned":        strings.Join(strings.Split(idp.sign(t, validClaims("users:read")), ".")[:2], ".") + ".",
+		"not even a JWT":  "Les Cles de Fort Boyard",
+		"tampered claims": tamperClaims(idp.sign(t, validClaims("users:read"))),
+	}
+	for name, token := range tokens {
+		responseRecorder := sendWithBearerToken(router, http.MethodGet, "/api/v1/users/username/kim", "", token)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Fatalf("the %s token has been answered with %d", name, responseRecorder.Code)
+		}
+	}
+}
+
+func TestGetUserByUsernameWithoutCredentialsWhenBearerTokensExpected_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	idp := newTestIdentityProvider(t)
+	_, router := core.SetupAppLayers(db, idp.config(nil))
+
+	responseRecorder := sendWithBearerToken(router, http.MethodGet, "/api/v1/users/username/kim", "", "")
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a key: %v", err)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "EC",
+		"kid": "test",
+		"use": "sig",
+		"alg": "ES256",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksPath, jwks, 0o600); err != nil {
+		t.Fatalf("failed to write the JWKS: %v", err)
+	}
+
+	return &testIdentityProvider{key: key, jwksPath: jwksPath}
+}
+
+func (idp *testIdentityProvider) config(scopeMapping map[string][]string) config.Config {
+	return config.Config{
+		JWKSSource:      idp.jwksPath,
+		JWTIssuer:       testIssuer,
+		JWTAudience:     testAudience,
+		JWTScopeMapping: scopeMapping,
+	}
+}
+
+func (idp *testIdentityProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, idp.key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign the token: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(scope string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "kim.kitsuragi",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": scope,
+	}
+}
+
+func tamperClaims(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"` + testIssuer + `","aud":"` + testAudience +
+		`","sub":"harrier.dubois","exp":9999999999,"scope":"users:read"}`))
+	return strings.Join(parts, ".")
+}
+
+func sendWithBearerToken(router *gin.Engine, method string, url string, body string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	return responseRecorder
+}
@@ -0,0 +1,79 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateUserWithUsernameDifferingInCase_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "KIM", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the username is already taken")
+}
+
+func TestCreateUserWithEmailDifferingInCase_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "klaasje", "email": "Kim.Kitsuragi@RCM.org"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the email is already in use")
+}
+
+func TestCreateUserStoresLowerCasedUsernameAndEmail_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "Klaasje", "email": "Klaasje.Amandou@NoName.com", "full_name": "Klaasje Amandou"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var userResponse map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &userResponse); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, userResponse, "klaasje", "Klaasje Amandou", "klaasje.amandou@noname.com")
+	if _, err := repositories.Users.GetByUsername(context.Background(), "KLAASJE"); err != nil {
+		t.Fatalf("user klaasje cannot be obtained from the DB regardless of case")
+	}
+}
+
+func TestGetUserByUsernameDifferingInCase_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/username/Tequila_Sunset", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	var userResponse map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &userResponse); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, userResponse, "tequila_sunset", "Harrier Du Bois", "harrier.dubois@rcm.org")
+}
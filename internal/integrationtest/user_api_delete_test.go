@@ -1,7 +1,10 @@
 package integrationtest
 
 import (
+	"context"
+	"cruder/internal/config"
 	"cruder/internal/core"
+	"cruder/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
@@ -13,8 +16,8 @@ func TestDeleteUserByUuid_Success(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
-	user, err := repositories.Users.GetByUsername(harryUsername)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+	user, err := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user == nil || err != nil {
 		t.Fatalf("user %s is expected to be present in the DB", harryUsername)
 	}
@@ -24,7 +27,7 @@ func TestDeleteUserByUuid_Success(t *testing.T) {
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
-	user, err = repositories.Users.GetByUsername(harryUsername)
+	user, err = repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user != nil || err == nil {
 		t.Fatalf("user %s is expected to be absent in the DB", harryUsername)
 	}
@@ -33,7 +36,7 @@ func TestDeleteUserByUuid_Success(t *testing.T) {
 func TestDeleteUserByInvalidUuid_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/worst-uuid-ever", nil)
 	responseRecorder := httptest.NewRecorder()
@@ -41,8 +44,8 @@ func TestDeleteUserByInvalidUuid_Failure(t *testing.T) {
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
 	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid UUID")
-	users, _ := repositories.Users.GetAll()
-	if len(users) != 2 {
+	page, _ := repositories.Users.GetAll(context.Background(), model.UserFilter{}, model.PageRequest{Limit: 10})
+	if len(page.Users) != 2 {
 		t.Fatalf("expected all users remain present in the DB")
 	}
 }
@@ -50,7 +53,7 @@ func TestDeleteUserByInvalidUuid_Failure(t *testing.T) {
 func TestDeleteUserByINonExistentUuid_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	randomUuid, _ := uuid.NewRandom()
 	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+randomUuid.String(), nil)
@@ -59,8 +62,8 @@ func TestDeleteUserByINonExistentUuid_Failure(t *testing.T) {
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
 	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
-	users, _ := repositories.Users.GetAll()
-	if len(users) != 2 {
+	page, _ := repositories.Users.GetAll(context.Background(), model.UserFilter{}, model.PageRequest{Limit: 10})
+	if len(page.Users) != 2 {
 		t.Fatalf("expected all users remain present in the DB")
 	}
 }
@@ -0,0 +1,90 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetAllUsersFilteredByUsernamePrefix_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?username_prefix=tequ", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(users))
+	}
+	assertThatUserFieldsAreExpected(t, users[0],
+		"tequila_sunset", "Harrier Du Bois", "harrier.dubois@rcm.org")
+}
+
+func TestGetAllUsersFilteredByEmailDomainAndFullName_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(
+		http.MethodGet, "/api/v1/users?email_domain=RCM.org&full_name_contains=kitsu", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(users))
+	}
+	assertThatUserFieldsAreExpected(t, users[0],
+		"kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+}
+
+func TestGetAllUsersFilteredByCreationTime_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(
+		http.MethodGet, "/api/v1/users?created_after=2000-01-01T00:00:00Z&created_before=2001-01-01T00:00:00Z", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 0 {
+		t.Fatalf("expected no users, got %d", len(users))
+	}
+}
+
+func TestGetAllUsersWithUnknownFilter_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?favourite_drink=tequila", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "unknown query parameter favourite_drink")
+}
+
+func TestGetAllUsersWithMalformedFilter_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?created_after=yesterday", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid created_after, RFC 3339 timestamp expected")
+}
@@ -1,9 +1,11 @@
 package integrationtest
 
 import (
+	"cruder/internal/config"
 	"cruder/internal/core"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,17 +14,14 @@ import (
 func TestGetAllUsers_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/", nil)
 	responseRecorder := httptest.NewRecorder()
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
-	var users []map[string]interface{}
-	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &users); err != nil {
-		t.Fatalf("invalid JSON: %v", err)
-	}
+	users := unmarshalUserPage(t, responseRecorder).Data
 	if len(users) != 2 {
 		t.Fatalf("expected 2 users added in this test, got %d", len(users))
 	}
@@ -35,17 +34,14 @@ func TestGetAllUsers_Success(t *testing.T) {
 func TestGetAllUsersOnEmptyDb_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := prepareDb(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/", nil)
 	responseRecorder := httptest.NewRecorder()
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
-	var users []map[string]interface{}
-	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &users); err != nil {
-		t.Fatalf("invalid JSON: %v", err)
-	}
+	users := unmarshalUserPage(t, responseRecorder).Data
 	if len(users) != 0 {
 		t.Fatalf("expected no users at all, got %d", len(users))
 	}
@@ -54,7 +50,7 @@ func TestGetAllUsersOnEmptyDb_Success(t *testing.T) {
 func TestGetUserByUsername_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/username/kim", nil)
 	responseRecorder := httptest.NewRecorder()
@@ -69,10 +65,41 @@ func TestGetUserByUsername_Success(t *testing.T) {
 		"kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
 }
 
+func TestGetUserByEmail_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/email/Kim.Kitsuragi@RCM.org", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	var user map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &user); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, user,
+		"kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+}
+
+func TestGetUserByNonExistentEmail_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/email/klaasje.amandou@noname.com", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
+
 func TestGetUserByNonExistentUsername_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/username/klaasje", nil)
 	responseRecorder := httptest.NewRecorder()
@@ -81,3 +108,60 @@ func TestGetUserByNonExistentUsername_Failure(t *testing.T) {
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
 	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
 }
+
+func TestGetUserByUuid_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, uuidKim := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/"+uuidKim.String(), nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	var user map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &user); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, user,
+		"kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+}
+
+func TestGetUserByNonExistentUuid_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	randomUuid, _ := uuid.NewRandom()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/"+randomUuid.String(), nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
+
+func TestGetUserByInvalidUuid_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/worst-uuid-ever", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid UUID")
+}
+
+func TestGetUserByIdWhenRouteDisabled_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IdRouteDisabled: true})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/id/1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+}
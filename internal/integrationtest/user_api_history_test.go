@@ -0,0 +1,157 @@
+package integrationtest
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type auditPage struct {
+	Data []struct {
+		Action    string                            `json:"action"`
+		Actor     string                            `json:"actor"`
+		RequestID string                            `json:"request_id"`
+		Changes   map[string]map[string]interface{} `json:"changes"`
+	} `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+}
+
+func TestGetUserHistoryByUuid_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), bytes.NewBuffer([]byte(body)))
+	req.Header.Set("X-Request-ID", "whirling-in-rags-1")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/v1/users/"+uuidHarry.String(), nil)
+	req.Header.Set("X-Request-ID", "whirling-in-rags-2")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/users/"+uuidHarry.String()+"/history", nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	history := unmarshalAuditPage(t, responseRecorder)
+	if len(history.Data) != 2 {
+		t.Fatalf("expected 2 audit entries, got %+v", history.Data)
+	}
+	deletion, update := history.Data[0], history.Data[1]
+	if deletion.Action != "delete" || deletion.RequestID != "whirling-in-rags-2" ||
+		deletion.Changes["deleted_at"]["before"] != nil || deletion.Changes["deleted_at"]["after"] == nil {
+		t.Fatalf("unexpected delete entry: %+v", deletion)
+	}
+	if update.Action != "update" || update.Actor != "anonymous" || update.RequestID != "whirling-in-rags-1" ||
+		len(update.Changes) != 1 ||
+		update.Changes["email"]["before"] != "harrier.dubois@rcm.org" ||
+		update.Changes["email"]["after"] != "raphael.costeau@rcm.org" {
+		t.Fatalf("unexpected update entry: %+v", update)
+	}
+}
+
+func TestGetUserHistoryOfCreatedUserPageByPage_Success(t *testing.T) {
+	key := "Les Cles de Fort Boyard"
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{XApiKey: key})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBuffer([]byte(body)))
+	req.Header.Set(apiHeaderKey, key)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var createdUser map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &createdUser); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	historyUrl := "/api/v1/users/" + createdUser["uuid"].(string) + "/history?limit=1"
+
+	body = `{"full_name": {"value": "Klaasje Amandou"}}`
+	req, _ = http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+createdUser["uuid"].(string), bytes.NewBuffer([]byte(body)))
+	req.Header.Set(apiHeaderKey, key)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+
+	req, _ = http.NewRequest(http.MethodGet, historyUrl, nil)
+	req.Header.Set(apiHeaderKey, key)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalAuditPage(t, responseRecorder)
+	if len(firstPage.Data) != 1 || firstPage.Data[0].Action != "update" || firstPage.NextCursor == nil {
+		t.Fatalf("unexpected first page: %+v", firstPage)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, historyUrl+"&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	req.Header.Set(apiHeaderKey, key)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	secondPage := unmarshalAuditPage(t, responseRecorder)
+	if len(secondPage.Data) != 1 || secondPage.NextCursor != nil || secondPage.PrevCursor == nil {
+		t.Fatalf("unexpected second page: %+v", secondPage)
+	}
+	creation := secondPage.Data[0]
+	if creation.Action != "create" || creation.Actor == "anonymous" ||
+		creation.Changes["username"]["after"] != "klaasje" || creation.Changes["full_name"] != nil {
+		t.Fatalf("unexpected create entry: %+v", creation)
+	}
+}
+
+func TestGetUserHistoryByNonExistentUuid_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	randomUuid, _ := uuid.NewRandom()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/"+randomUuid.String()+"/history", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
+
+func TestAuditLogIsAppendOnly_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, _ := core.SetupAppLayers(db, config.Config{})
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM user_audit_log`); err == nil {
+		t.Fatalf("audit entries are expected to be undeletable")
+	}
+}
+
+func unmarshalAuditPage(t *testing.T, responseRecorder *httptest.ResponseRecorder) auditPage {
+	t.Helper()
+
+	var page auditPage
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	return page
+}
@@ -0,0 +1,157 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateUserRepeatedWithIdempotencyKey_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{IdempotencyKeyTTL: time.Hour})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	firstResponseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(firstResponseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, firstResponseRecorder, http.StatusCreated)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	secondResponseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(secondResponseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, secondResponseRecorder, http.StatusCreated)
+	if secondResponseRecorder.Body.String() != firstResponseRecorder.Body.String() ||
+		secondResponseRecorder.Header().Get("ETag") != firstResponseRecorder.Header().Get("ETag") ||
+		secondResponseRecorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("the response has not been replayed: %s", secondResponseRecorder.Body.String())
+	}
+	page, _ := repositories.Users.GetAll(context.Background(), model.UserFilter{}, model.PageRequest{Limit: 10})
+	if len(page.Users) != 3 {
+		t.Fatalf("exactly one user must have been created, got %d users", len(page.Users))
+	}
+}
+
+func TestCreateUserWithReusedIdempotencyKey_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IdempotencyKeyTTL: time.Hour})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+
+	body = `{"username": "cuno", "email": "cuno@noname.com"}`
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatErrorMessageIsExpected(t, responseRecorder,
+		"the Idempotency-Key has already been used for a different request")
+}
+
+func TestBatchUsersWithIdempotencyKeyReusedForOtherQuery_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IdempotencyKeyTTL: time.Hour})
+
+	body := `{"operations": [{"op": "create", "user": {"username": "klaasje", "email": "klaasje.amandou@noname.com"}}]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch?atomic=true", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users:batch?atomic=false", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatErrorMessageIsExpected(t, responseRecorder,
+		"the Idempotency-Key has already been used for a different request")
+}
+
+func TestCreateUserWithExpiredIdempotencyKey_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IdempotencyKeyTTL: time.Hour})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	if _, err := db.Exec(`UPDATE idempotency_keys SET expires_at = now() - interval '1 minute'`); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the username is already taken")
+}
+
+func TestCreateUserWithIdempotencyKeyOfStaleClaim_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IdempotencyKeyTTL: time.Hour, IdempotencyLease: time.Minute})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	// as if the request had crashed before its outcome was recorded
+	if _, err := db.Exec(`DELETE FROM users WHERE username = 'klaasje'`); err != nil {
+		t.Fatalf("failed to delete data: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE idempotency_keys
+		SET status_code = NULL, headers = NULL, body = NULL, locked_until = now() + interval '1 minute'`); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder,
+		"a request with the same Idempotency-Key is still being processed")
+	if _, err := db.Exec(`UPDATE idempotency_keys SET locked_until = now() - interval '1 second'`); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "whirling-in-rags")
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+}
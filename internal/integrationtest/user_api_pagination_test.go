@@ -0,0 +1,111 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGetAllUsersPageByPage_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalUserPage(t, responseRecorder)
+	if len(firstPage.Data) != 1 || firstPage.NextCursor == nil || firstPage.PrevCursor != nil {
+		t.Fatalf("unexpected first page: %+v", firstPage)
+	}
+	assertThatUserFieldsAreExpected(t, firstPage.Data[0],
+		"tequila_sunset", "Harrier Du Bois", "harrier.dubois@rcm.org")
+
+	req, _ = http.NewRequest(
+		http.MethodGet, "/api/v1/users?limit=1&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	secondPage := unmarshalUserPage(t, responseRecorder)
+	if len(secondPage.Data) != 1 || secondPage.NextCursor != nil || secondPage.PrevCursor == nil {
+		t.Fatalf("unexpected second page: %+v", secondPage)
+	}
+	assertThatUserFieldsAreExpected(t, secondPage.Data[0],
+		"kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+
+	req, _ = http.NewRequest(
+		http.MethodGet, "/api/v1/users?limit=1&cursor="+url.QueryEscape(*secondPage.PrevCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	previousPage := unmarshalUserPage(t, responseRecorder)
+	if len(previousPage.Data) != 1 || previousPage.NextCursor == nil || previousPage.PrevCursor != nil {
+		t.Fatalf("unexpected previous page: %+v", previousPage)
+	}
+	assertThatUserFieldsAreExpected(t, previousPage.Data[0],
+		"tequila_sunset", "Harrier Du Bois", "harrier.dubois@rcm.org")
+}
+
+func TestGetAllUsersWithNullFullNameLast_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+	if _, err := db.Exec(`UPDATE users SET full_name = NULL WHERE username = 'tequila_sunset'`); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalUserPage(t, responseRecorder)
+	if len(firstPage.Data) != 1 || firstPage.Data[0]["username"] != "kim" || firstPage.NextCursor == nil {
+		t.Fatalf("unexpected first page: %+v", firstPage)
+	}
+
+	req, _ = http.NewRequest(
+		http.MethodGet, "/api/v1/users?limit=1&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	secondPage := unmarshalUserPage(t, responseRecorder)
+	if len(secondPage.Data) != 1 || secondPage.Data[0]["username"] != "tequila_sunset" || secondPage.NextCursor != nil {
+		t.Fatalf("unexpected second page: %+v", secondPage)
+	}
+}
+
+func TestGetAllUsersWithInvalidCursor_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?cursor=the-pale", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid cursor")
+}
+
+func TestGetAllUsersWithInvalidLimit_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?limit=1000", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid limit")
+}
@@ -0,0 +1,105 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMergePatchUserByUuid_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "raphael.costeau@rcm.org", "full_name": null}`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Username != "tequila_sunset" || user.Email != "raphael.costeau@rcm.org" || user.FullName.Valid {
+		t.Fatalf("user has unexpected fields: %+v", user)
+	}
+}
+
+func TestMergePatchUserByUuidClearingUsername_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": null}`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "username")
+}
+
+func TestJSONPatchUserByUuid_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `[
+		{"op": "test", "path": "/username", "value": "tequila_sunset"},
+		{"op": "replace", "path": "/username", "value": "raphael"},
+		{"op": "test", "path": "/username", "value": "raphael"},
+		{"op": "remove", "path": "/full_name"}
+	]`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Username != "raphael" || user.FullName.Valid {
+		t.Fatalf("user has unexpected fields: %+v", user)
+	}
+}
+
+func TestJSONPatchUserByUuidWithFailingTest_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `[
+		{"op": "test", "path": "/full_name", "value": null},
+		{"op": "replace", "path": "/username", "value": "raphael"}
+	]`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the patch test has failed")
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Username != "tequila_sunset" {
+		t.Fatalf("user has an unexpected username %s", user.Username)
+	}
+}
+
+func TestJSONPatchUserByUuidWithUnsupportedOperation_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `[{"op": "move", "from": "/email", "path": "/username"}]`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "/0/op")
+}
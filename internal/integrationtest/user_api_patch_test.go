@@ -2,6 +2,8 @@ package integrationtest
 
 import (
 	"bytes"
+	"context"
+	"cruder/internal/config"
 	"cruder/internal/core"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,7 +16,7 @@ func TestPatchUserByUuid_Success(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"full_name": {"value": "Raphaël Ambrosius Costeau"}}`
 	req, _ := http.NewRequest(
@@ -23,7 +25,7 @@ func TestPatchUserByUuid_Success(t *testing.T) {
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
-	user, _ := repositories.Users.GetByUsername(harryUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user.FullName.String != "Raphaël Ambrosius Costeau" {
 		t.Fatalf("user %s has an unxpected full name %s", harryUsername, user.FullName.String)
 	}
@@ -33,7 +35,7 @@ func TestPatchUserByUuidWithNullFullName_Success(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"full_name": null}`
 	req, _ := http.NewRequest(
@@ -42,7 +44,7 @@ func TestPatchUserByUuidWithNullFullName_Success(t *testing.T) {
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
-	user, _ := repositories.Users.GetByUsername(harryUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if !user.FullName.Valid {
 		t.Fatalf("user %s has an unxpected NULL full name", harryUsername)
 	}
@@ -52,7 +54,7 @@ func TestPatchUserByUuidWithExplicitlyErasedFullName_Success(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"full_name": {"value": null}}`
 	req, _ := http.NewRequest(
@@ -61,7 +63,7 @@ func TestPatchUserByUuidWithExplicitlyErasedFullName_Success(t *testing.T) {
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
-	user, _ := repositories.Users.GetByUsername(harryUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user.FullName.Valid {
 		t.Fatalf("user %s has an unxpected full name %s", harryUsername, user.FullName.String)
 	}
@@ -71,7 +73,7 @@ func TestPatchUserByUuidWithImplicitlyErasedFullName_Success(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"full_name": {}}`
 	req, _ := http.NewRequest(
@@ -80,7 +82,7 @@ func TestPatchUserByUuidWithImplicitlyErasedFullName_Success(t *testing.T) {
 	router.ServeHTTP(responseRecorder, req)
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
-	user, _ := repositories.Users.GetByUsername(harryUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user.FullName.Valid {
 		t.Fatalf("user %s has an unxpected full name %s", harryUsername, user.FullName.String)
 	}
@@ -90,7 +92,7 @@ func TestPatchUserByUuidWithExistingUsername_Failure(t *testing.T) {
 	kimUsername := "kim"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "kim"}`
 	req, _ := http.NewRequest(
@@ -100,7 +102,7 @@ func TestPatchUserByUuidWithExistingUsername_Failure(t *testing.T) {
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
 	assertThatErrorMessageIsExpected(t, responseRecorder, "the username is already taken")
-	user, _ := repositories.Users.GetByUsername(kimUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), kimUsername)
 	if user.FullName.String != "Kim Kitsuragi" {
 		t.Fatalf("user %s has an unxpected full name %s", kimUsername, user.FullName.String)
 	}
@@ -110,7 +112,7 @@ func TestPatchUserByUuidWithExistingEmail_Failure(t *testing.T) {
 	harryUsername := "tequila_sunset"
 	gin.SetMode(gin.TestMode)
 	db, uuidHarry, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"email": "kim.kitsuragi@rcm.org"}`
 	req, _ := http.NewRequest(
@@ -120,7 +122,7 @@ func TestPatchUserByUuidWithExistingEmail_Failure(t *testing.T) {
 
 	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
 	assertThatErrorMessageIsExpected(t, responseRecorder, "the email is already in use")
-	user, _ := repositories.Users.GetByUsername(harryUsername)
+	user, _ := repositories.Users.GetByUsername(context.Background(), harryUsername)
 	if user.Email != "harrier.dubois@rcm.org" {
 		t.Fatalf("user %s has an unxpected email %s", harryUsername, user.Email)
 	}
@@ -129,7 +131,7 @@ func TestPatchUserByUuidWithExistingEmail_Failure(t *testing.T) {
 func TestPatchUserByUuidWithNonExistentUuid_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 	body := `{"username": "klaasje"}`
 	randomUuid, _ := uuid.NewRandom()
 
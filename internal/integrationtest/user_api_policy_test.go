@@ -0,0 +1,178 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const supportKey = "Kineema ignition key"
+const adminKey = "Lieutenant double-yefreitor badge"
+
+var policyConfig = config.Config{
+	APIKeys: []config.APIKey{
+		{Name: "support", Key: supportKey, Scopes: model.AllScopes, Roles: []string{"support"}},
+		{Name: "admin", Key: adminKey, Scopes: model.AllScopes, Roles: []string{"admin"}},
+	},
+	UserPolicy: &model.UserPolicy{Roles: map[string]model.RolePolicy{
+		"support": {
+			Actions: []string{model.PolicyActionRead, model.PolicyActionUpdate},
+			Fields:  []string{"full_name"},
+		},
+		"admin": {
+			Actions: []string{
+				model.PolicyActionRead,
+				model.PolicyActionCreate,
+				model.PolicyActionUpdate,
+				model.PolicyActionDelete,
+				model.PolicyActionRestore,
+			},
+			Fields: model.PolicyFields,
+		},
+	}},
+}
+
+func TestPatchUserByUuidWithAllowedField_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, policyConfig)
+
+	body := `{"full_name": {"value": "Raphael Ambrosius Costeau"}}`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, supportKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.FullName.String != "Raphael Ambrosius Costeau" {
+		t.Fatalf("user has an unexpected full name %s", user.FullName.String)
+	}
+}
+
+func TestPatchUserByUuidWithDeniedFields_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, policyConfig)
+
+	body := `{"email": "raphael.costeau@rcm.org", "full_name": {"value": "Raphael Ambrosius Costeau"}}`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, supportKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the update action is not allowed on email")
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "email")
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Email != "harrier.dubois@rcm.org" {
+		t.Fatalf("user has an unexpected email %s", user.Email)
+	}
+}
+
+func TestJSONPatchUserByUuidWithDeniedField_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, policyConfig)
+
+	body := `[
+		{"op": "test", "path": "/email", "value": "harrier.dubois@rcm.org"},
+		{"op": "replace", "path": "/username", "value": "raphael"}
+	]`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	req.Header.Set(apiHeaderKey, supportKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "username")
+}
+
+func TestDeleteUserByUuidWithoutDeleteAction_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, policyConfig)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+uuidHarry.String(), nil)
+	req.Header.Set(apiHeaderKey, supportKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the delete action is not allowed")
+}
+
+func TestDeleteUserByUuidWithDeleteAction_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, policyConfig)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+uuidHarry.String(), nil)
+	req.Header.Set(apiHeaderKey, adminKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+}
+
+func TestBatchUsersWithDeniedOperation_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, policyConfig)
+
+	patch := `{"full_name": {"value": "Raphael Ambrosius Costeau"}}`
+	body := `{"operations": [
+		{"op": "patch", "uuid": "` + uuidHarry.String() + `", "patch": ` + patch + `},
+		{"op": "create", "user": {"username": "klaasje", "email": "klaasje.amandou@noname.com"}}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch?atomic=false", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, supportKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "operation 1: the create action is not allowed")
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.FullName.String == "Raphael Ambrosius Costeau" {
+		t.Fatalf("no operation of a denied batch must be executed")
+	}
+}
+
+func TestPatchUserByUuidWithStoredKeyRole_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, policyConfig)
+	support := mintAPIKey(t, router, adminKey,
+		`{"name": "support", "scopes": ["users:read", "users:write"], "roles": ["support"]}`)
+
+	body := `{"email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, support.Key)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "email")
+}
+
+func TestMintAPIKeyWithUnknownRole_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, policyConfig)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/api-keys",
+		strings.NewReader(`{"name": "support", "scopes": ["users:read"], "roles": ["superintendent"]}`))
+	req.Header.Set(apiHeaderKey, adminKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, `the role "superintendent" is not defined by the user policy`)
+}
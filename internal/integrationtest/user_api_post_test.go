@@ -2,6 +2,8 @@ package integrationtest
 
 import (
 	"bytes"
+	"context"
+	"cruder/internal/config"
 	"cruder/internal/core"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
@@ -16,7 +18,7 @@ func TestCreateUser_Success(t *testing.T) {
 	klaasjeEmail := "klaasje.amandou@noname.com"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "klaasje", "full_name": "Klaasje Amandou", "email": "klaasje.amandou@noname.com"}`
 	req, _ := http.NewRequest(
@@ -31,7 +33,7 @@ func TestCreateUser_Success(t *testing.T) {
 	}
 	assertThatUserFieldsAreExpected(t, userResponse,
 		klaasjeUserName, klaasjeFullName, klaasjeEmail)
-	user, err := repositories.Users.GetByUsername(klaasjeUserName)
+	user, err := repositories.Users.GetByUsername(context.Background(), klaasjeUserName)
 	if err != nil {
 		t.Fatalf("user %s cannot be obtained from the DB", klaasjeUserName)
 	}
@@ -45,7 +47,7 @@ func TestCreateUserWithoutFullName_Success(t *testing.T) {
 	klaasjeEmail := "klaasje.amandou@noname.com"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	repositories, router := core.SetupAppLayers(db, "")
+	repositories, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
 	req, _ := http.NewRequest(
@@ -60,7 +62,7 @@ func TestCreateUserWithoutFullName_Success(t *testing.T) {
 	}
 	assertThatUsernameAndEmailAreExpected(t, userResponse,
 		klaasjeUserName, klaasjeEmail)
-	user, err := repositories.Users.GetByUsername(klaasjeUserName)
+	user, err := repositories.Users.GetByUsername(context.Background(), klaasjeUserName)
 	if err != nil {
 		t.Fatalf("user %s cannot be obtained from the DB", klaasjeUserName)
 	}
@@ -74,7 +76,7 @@ func TestCreateUserOnSlightlyDifferentUrl_Success(t *testing.T) {
 	klaasjeEmail := "klaasje.amandou@noname.com"
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
 	req, _ := http.NewRequest(
@@ -94,7 +96,7 @@ func TestCreateUserOnSlightlyDifferentUrl_Success(t *testing.T) {
 func TestCreateUserWithExistingUsername_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "kim", "email": "klaasje.amandou@noname.com"}`
 	req, _ := http.NewRequest(
@@ -109,7 +111,7 @@ func TestCreateUserWithExistingUsername_Failure(t *testing.T) {
 func TestCreateUserWithExistingEmail_Failure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _, _ := prepareDbWithTestData(t)
-	_, router := core.SetupAppLayers(db, "")
+	_, router := core.SetupAppLayers(db, config.Config{})
 
 	body := `{"username": "klaasje", "email": "kim.kitsuragi@rcm.org"}`
 	req, _ := http.NewRequest(
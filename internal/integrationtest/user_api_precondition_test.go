@@ -0,0 +1,112 @@
+package integrationtest
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUserByUuidReturnsETag_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, uuidKim := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/"+uuidKim.String(), nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	assertThatETagIsExpected(t, responseRecorder, `"1"`)
+}
+
+func TestPatchUserByUuidWithMatchingIfMatch_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{IfMatchRequired: true})
+
+	body := `{"full_name": {"value": "Raphaël Ambrosius Costeau"}}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), bytes.NewBuffer([]byte(body)))
+	req.Header.Set("If-Match", `"1"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	assertThatETagIsExpected(t, responseRecorder, `"2"`)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.FullName.String != "Raphaël Ambrosius Costeau" || user.Version != 2 {
+		t.Fatalf("user has unexpected fields: %+v", user)
+	}
+}
+
+func TestPatchUserByUuidWithStaleIfMatch_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"full_name": {"value": "Raphaël Ambrosius Costeau"}}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), bytes.NewBuffer([]byte(body)))
+	req.Header.Set("If-Match", `"7"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusPreconditionFailed)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the user has been modified in the meantime")
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.FullName.String != "Harrier Du Bois" {
+		t.Fatalf("user has an unexpected full name %s", user.FullName.String)
+	}
+}
+
+func TestPatchUserByUuidWithoutIfMatchInStrictMode_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{IfMatchRequired: true})
+
+	body := `{"full_name": {"value": "Raphaël Ambrosius Costeau"}}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), bytes.NewBuffer([]byte(body)))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusPreconditionRequired)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the If-Match header is required")
+}
+
+func TestDeleteUserByUuidWithStaleIfMatch_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+uuidHarry.String(), nil)
+	req.Header.Set("If-Match", `W/"1"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusPreconditionFailed)
+	if user, err := repositories.Users.GetByUuid(context.Background(), uuidHarry); user == nil || err != nil {
+		t.Fatalf("user is expected to be present in the DB")
+	}
+}
+
+func TestDeleteUserByNonExistentUuidWithIfMatch_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	randomUuid, _ := uuid.NewRandom()
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+randomUuid.String(), nil)
+	req.Header.Set("If-Match", `"1"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
@@ -0,0 +1,35 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUserByUnknownUuidReturnsProblemDetails_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+	randomUuid, _ := uuid.NewRandom()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/"+randomUuid.String(), nil)
+	req.Header.Set("X-Request-ID", "revachol-42")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+	var problem map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if problem["type"] != "about:blank" || problem["title"] != "Not Found" ||
+		problem["instance"] != "/api/v1/users/"+randomUuid.String() || problem["request_id"] != "revachol-42" {
+		t.Fatalf("unexpected problem details: %+v", problem)
+	}
+}
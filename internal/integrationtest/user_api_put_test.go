@@ -0,0 +1,90 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPutUserByUuidClearsOmittedFields_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "raphael", "email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("If-Match", `"1"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	assertThatETagIsExpected(t, responseRecorder, `"2"`)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Username != "raphael" || user.Email != "raphael.costeau@rcm.org" || user.FullName.Valid {
+		t.Fatalf("user has unexpected fields: %+v", user)
+	}
+}
+
+func TestPutUserByUuidWithTakenUsername_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "kim", "email": "harrier.dubois@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the username is already taken")
+}
+
+func TestPutUserByUuidWithInvalidFields_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "tequila_sunset"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "email")
+}
+
+func TestPutUserByUuidWithStaleIfMatch_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "raphael", "email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	req.Header.Set("If-Match", `"7"`)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusPreconditionFailed)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the user has been modified in the meantime")
+}
+
+func TestPutUserByUnknownUuid_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+	randomUuid, _ := uuid.NewRandom()
+
+	body := `{"username": "raphael", "email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+randomUuid.String(), strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
@@ -0,0 +1,161 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const readerKey = "Whirling-in-Rags guest book"
+const writerKey = "Precinct 41 badge"
+const usersAdminKey = "Precinct 57 evidence locker key"
+
+var scopedKeysConfig = config.Config{APIKeys: []config.APIKey{
+	{Name: "reader", Key: readerKey, Scopes: []string{model.ScopeUsersRead}},
+	{Name: "writer", Key: writerKey, Scopes: []string{model.ScopeUsersRead, model.ScopeUsersWrite}},
+	{Name: "users-admin", Key: usersAdminKey, Scopes: []string{model.ScopeUsersAdmin}},
+}}
+
+func TestGetUserByUsernameWithReadScope_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/username/kim", nil)
+	req.Header.Set(apiHeaderKey, readerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
+
+func TestCreateUserWithoutWriteScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, readerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:write")
+}
+
+func TestCreateUserWithWriteScopeRecordsKeyName_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, writerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var createdUser map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &createdUser); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/users/"+createdUser["uuid"].(string)+"/history", nil)
+	req.Header.Set(apiHeaderKey, readerKey)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	entries := unmarshalAuditPage(t, responseRecorder).Data
+	if len(entries) != 1 || entries[0].Actor != "api-key:writer" {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
+
+func TestDeleteUserWithoutDeleteScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/"+uuidHarry.String(), nil)
+	req.Header.Set(apiHeaderKey, writerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:delete")
+}
+
+func TestBatchUsersDeletingWithoutDeleteScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	body := `{"operations": [{"op": "delete", "uuid": "` + uuidHarry.String() + `"}]}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users:batch", strings.NewReader(body))
+	req.Header.Set(apiHeaderKey, writerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:delete")
+}
+
+func TestGetAllDeletedUsersWithoutAdminScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, scopedKeysConfig)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/deleted", nil)
+	req.Header.Set(apiHeaderKey, readerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:admin")
+}
+
+func TestRestoreUserWithoutAdminScope_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, scopedKeysConfig)
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users/"+uuidHarry.String()+"/restore", nil)
+	req.Header.Set(apiHeaderKey, writerKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusForbidden)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "missing scope users:admin")
+}
+
+func TestRestoreUserWithAdminScope_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, scopedKeysConfig)
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/deleted", nil)
+	req.Header.Set(apiHeaderKey, usersAdminKey)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+uuidHarry.String()+"/restore", nil)
+	req.Header.Set(apiHeaderKey, usersAdminKey)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
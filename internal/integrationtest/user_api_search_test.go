@@ -0,0 +1,112 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSearchUsersByPartialName_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/search?q=kitsu", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(users))
+	}
+	assertThatUserFieldsAreExpected(t, users[0], "kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+	if score, _ := users[0]["score"].(float64); score <= 0 || score > 1 {
+		t.Fatalf("unexpected score %v", users[0]["score"])
+	}
+}
+
+func TestSearchUsersWithMisspelling_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/search?q=Kitsuragy", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(users))
+	}
+	assertThatUserFieldsAreExpected(t, users[0], "kim", "Kim Kitsuragi", "kim.kitsuragi@rcm.org")
+}
+
+func TestSearchUsersPaginated_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/search?q=rcm.org&limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalUserPage(t, responseRecorder)
+	if len(firstPage.Data) != 1 || firstPage.NextCursor == nil || firstPage.PrevCursor != nil {
+		t.Fatalf("unexpected first page: %+v", firstPage)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet,
+		"/api/v1/users/search?q=rcm.org&limit=1&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	secondPage := unmarshalUserPage(t, responseRecorder)
+	if len(secondPage.Data) != 1 || secondPage.NextCursor != nil || secondPage.PrevCursor == nil {
+		t.Fatalf("unexpected second page: %+v", secondPage)
+	}
+	if firstPage.Data[0]["uuid"] == secondPage.Data[0]["uuid"] {
+		t.Fatalf("the same user has been returned twice")
+	}
+	if firstPage.Data[0]["score"].(float64) < secondPage.Data[0]["score"].(float64) {
+		t.Fatalf("users are not ranked by score")
+	}
+}
+
+func TestSearchUsersWithoutQuery_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/search?q=%20", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "query parameter q must contain between 1 and 100 characters")
+}
+
+func TestSearchUsersWithListCursor_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/?limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	listCursor := unmarshalUserPage(t, responseRecorder).NextCursor
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/users/search?q=kim&cursor="+url.QueryEscape(*listCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid cursor")
+}
@@ -0,0 +1,144 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const signingKeyName = "precinct"
+const signingSecret = "Precinct 41 shared secret"
+
+var signingConfig = config.Config{
+	APIKeys: []config.APIKey{{
+		Name:              signingKeyName,
+		Key:               signingSecret,
+		Scopes:            []string{model.ScopeUsersRead, model.ScopeUsersWrite},
+		SignatureRequired: true,
+	}},
+	SignatureWindow: time.Minute,
+}
+
+func TestCreateUserWithSignedRequest_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req := newSignedRequest(http.MethodPost, "/api/v1/users", body, time.Now(), "nonce-1")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+}
+
+func TestReplayedSignedRequest_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+	now := time.Now()
+
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, newSignedRequest(http.MethodGet, "/api/v1/users/username/kim", "", now, "nonce-1"))
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, newSignedRequest(http.MethodGet, "/api/v1/users/username/kim", "", now, "nonce-1"))
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the signature nonce has already been used")
+}
+
+func TestSignedRequestWithTamperedBody_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req := newSignedRequest(http.MethodPost, "/api/v1/users", body, time.Now(), "nonce-1")
+	req.Body = http.NoBody
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid signature")
+}
+
+func TestSignedRequestWithStaleTimestamp_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	req := newSignedRequest(http.MethodGet, "/api/v1/users/username/kim", "", time.Now().Add(-time.Hour), "nonce-1")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the signature timestamp is outside the accepted window")
+}
+
+func TestUnsignedRequestWithSigningKey_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/username/kim", nil)
+	req.Header.Set(apiHeaderKey, signingSecret)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the API key may only sign requests")
+}
+
+func TestSignedRequestWithOversizedBody_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com", "padding": "` +
+		strings.Repeat("x", 2<<20) + `"}`
+	req := newSignedRequest(http.MethodPost, "/api/v1/users", body, time.Now(), "nonce-1")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusRequestEntityTooLarge)
+}
+
+func TestSignedRequestWithUnknownKey_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, signingConfig)
+
+	req := newSignedRequest(http.MethodGet, "/api/v1/users/username/kim", "", time.Now(), "nonce-1")
+	req.Header.Set("X-Signature-Key", "evrart")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnauthorized)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid signature")
+}
+
+func newSignedRequest(method string, url string, body string, timestamp time.Time, nonce string) *http.Request {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	timestampStr := strconv.FormatInt(timestamp.Unix(), 10)
+	bodyDigest := sha256.Sum256([]byte(body))
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(method + "\n" + url + "\n" + timestampStr + "\n" + nonce + "\n" + hex.EncodeToString(bodyDigest[:])))
+
+	req.Header.Set("X-Signature-Key", signingKeyName)
+	req.Header.Set("X-Signature-Timestamp", timestampStr)
+	req.Header.Set("X-Signature-Nonce", nonce)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
@@ -0,0 +1,100 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/service"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRestoreDeletedUserByUuid_Success(t *testing.T) {
+	harryUsername := "tequila_sunset"
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user %s: %v", harryUsername, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users/"+uuidHarry.String()+"/restore", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	var userResponse map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &userResponse); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, userResponse,
+		harryUsername, "Harrier Du Bois", "harrier.dubois@rcm.org")
+	user, err := repositories.Users.GetByUsername(context.Background(), harryUsername)
+	if user == nil || err != nil {
+		t.Fatalf("user %s is expected to be present in the DB", harryUsername)
+	}
+}
+
+func TestRestoreNotDeletedUserByUuid_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, uuidKim := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users/"+uuidKim.String()+"/restore", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNotFound)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "users not found")
+}
+
+func TestGetAllDeletedUsers_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/deleted", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 1 || users[0]["deleted_at"] == nil {
+		t.Fatalf("expected 1 deleted user, got %+v", users)
+	}
+	assertThatUserFieldsAreExpected(t, users[0],
+		"tequila_sunset", "Harrier Du Bois", "harrier.dubois@rcm.org")
+}
+
+func TestPurgeDeletedUsersAfterRetention_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, uuidKim := prepareDbWithTestData(t)
+	repositories, _ := core.SetupAppLayers(db, config.Config{})
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidKim, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if _, err := db.Exec(
+		`UPDATE users SET deleted_at = now() - interval '31 days' WHERE uuid = $1`, uuidHarry); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	service.NewDeletedUsersPurger(repositories.Users, 30*24*time.Hour, time.Hour).Purge(context.Background())
+
+	var remainingUsersCount int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&remainingUsersCount); err != nil {
+		t.Fatalf("failed to count users: %v", err)
+	}
+	if remainingUsersCount != 1 {
+		t.Fatalf("expected only the recently deleted user to remain, got %d users", remainingUsersCount)
+	}
+}
@@ -0,0 +1,88 @@
+package integrationtest
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGetAllUsersSortedByUsernameDescending_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?sort=-username", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	users := unmarshalUserPage(t, responseRecorder).Data
+	if len(users) != 2 || users[0]["username"] != "tequila_sunset" || users[1]["username"] != "kim" {
+		t.Fatalf("unexpected users order: %+v", users)
+	}
+}
+
+func TestGetAllUsersSortedByMultipleKeysPageByPage_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	// both test users share the same created_at, so the second key decides
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?sort=-created_at,username&limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalUserPage(t, responseRecorder)
+	if len(firstPage.Data) != 1 || firstPage.Data[0]["username"] != "kim" || firstPage.NextCursor == nil {
+		t.Fatalf("unexpected first page: %+v", firstPage)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet,
+		"/api/v1/users?sort=-created_at,username&limit=1&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	secondPage := unmarshalUserPage(t, responseRecorder)
+	if len(secondPage.Data) != 1 || secondPage.Data[0]["username"] != "tequila_sunset" || secondPage.NextCursor != nil {
+		t.Fatalf("unexpected second page: %+v", secondPage)
+	}
+}
+
+func TestGetAllUsersWithCursorOfAnotherSort_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?sort=email&limit=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	firstPage := unmarshalUserPage(t, responseRecorder)
+
+	req, _ = http.NewRequest(http.MethodGet,
+		"/api/v1/users?sort=username&limit=1&cursor="+url.QueryEscape(*firstPage.NextCursor), nil)
+	responseRecorder = httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid cursor")
+}
+
+func TestGetAllUsersSortedByUnknownField_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?sort=id", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusBadRequest)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "invalid sort")
+}
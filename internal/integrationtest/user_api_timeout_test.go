@@ -0,0 +1,54 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetAllUsersWithinDBTimeout_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{DBTimeout: time.Minute})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+}
+
+func TestGetAllUsersExceedingDBTimeout_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{DBTimeout: time.Nanosecond})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusGatewayTimeout)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the request took too long to process")
+}
+
+func TestCreateUserExceedingDBTimeout_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{DBTimeout: time.Nanosecond})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusGatewayTimeout)
+	if _, err := repositories.Users.GetByUsername(context.Background(), "klaasje"); err == nil {
+		t.Fatalf("the user must not have been created")
+	}
+}
@@ -0,0 +1,85 @@
+package integrationtest
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateUserReturnsTimestamps_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "klaasje", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(
+		http.MethodPost, "/api/v1/users", bytes.NewBuffer([]byte(body)))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	var userResponse map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &userResponse); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	createdAt := parseTimestamp(t, userResponse["created_at"])
+	updatedAt := parseTimestamp(t, userResponse["updated_at"])
+	if time.Since(createdAt) > time.Minute || !updatedAt.Equal(createdAt) {
+		t.Fatalf("unexpected timestamps: %+v", userResponse)
+	}
+}
+
+func TestPatchUserByUuidMaintainsUpdatedAt_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), bytes.NewBuffer([]byte(body)))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if !user.UpdatedAt.After(user.CreatedAt) {
+		t.Fatalf("expected updated_at %v to be after created_at %v", user.UpdatedAt, user.CreatedAt)
+	}
+}
+
+func TestRawSqlUpdateMaintainsUpdatedAt_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, uuidKim := prepareDbWithTestData(t)
+	repositories, _ := core.SetupAppLayers(db, config.Config{})
+
+	if _, err := db.Exec(`UPDATE users SET full_name = 'Lieutenant Kitsuragi' WHERE uuid = $1`, uuidKim); err != nil {
+		t.Fatalf("failed to update data: %v", err)
+	}
+
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidKim)
+	if !user.UpdatedAt.After(user.CreatedAt) {
+		t.Fatalf("expected updated_at %v to be after created_at %v", user.UpdatedAt, user.CreatedAt)
+	}
+}
+
+func parseTimestamp(t *testing.T, value interface{}) time.Time {
+	t.Helper()
+
+	timestamp, ok := value.(string)
+	if !ok {
+		t.Fatalf("timestamp expected, got %v", value)
+	}
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		t.Fatalf("RFC 3339 timestamp expected, got %s", timestamp)
+	}
+
+	return parsed
+}
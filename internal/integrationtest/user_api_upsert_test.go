@@ -0,0 +1,87 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPutUserByUnknownUsernameCreatesUser_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "klaasje.amandou@noname.com", "full_name": "Klaasje Amandou"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/username/klaasje", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	assertThatETagIsExpected(t, responseRecorder, `"1"`)
+	var userResponse map[string]interface{}
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &userResponse); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	assertThatUserFieldsAreExpected(t, userResponse, "klaasje", "Klaasje Amandou", "klaasje.amandou@noname.com")
+	if _, err := repositories.Users.GetByUsername(context.Background(), "klaasje"); err != nil {
+		t.Fatalf("user klaasje cannot be obtained from the DB")
+	}
+}
+
+func TestPutUserByExistingUsernameUpdatesUser_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/username/tequila_sunset", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusOK)
+	assertThatETagIsExpected(t, responseRecorder, `"2"`)
+	user, _ := repositories.Users.GetByUsername(context.Background(), "tequila_sunset")
+	if user.UUID != uuidHarry || user.Email != "raphael.costeau@rcm.org" || user.FullName.Valid {
+		t.Fatalf("user has unexpected fields: %+v", user)
+	}
+}
+
+func TestPutUserByUsernameWithTakenEmail_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "kim.kitsuragi@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/username/klaasje", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusConflict)
+	assertThatErrorMessageIsExpected(t, responseRecorder, "the email is already in use")
+}
+
+func TestPutUserByUsernameOfDeletedUserCreatesUser_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+	if err := repositories.Users.DeleteByUuid(context.Background(), uuidHarry, nil, testAuditContext); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	body := `{"email": "raphael.costeau@rcm.org"}`
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/username/tequila_sunset", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusCreated)
+	user, _ := repositories.Users.GetByUsername(context.Background(), "tequila_sunset")
+	if user == nil || user.UUID == uuidHarry {
+		t.Fatalf("a new user must have been created: %+v", user)
+	}
+}
@@ -0,0 +1,104 @@
+package integrationtest
+
+import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/core"
+	"cruder/internal/model"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type validationProblem struct {
+	Detail string `json:"detail"`
+	Errors []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func TestCreateUserWithInvalidFields_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "", "email": "x", "full_name": "Klaasje\nAmandou"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "username", "email", "full_name")
+	page, _ := repositories.Users.GetAll(context.Background(), model.UserFilter{}, model.PageRequest{Limit: 10})
+	if len(page.Users) != 2 {
+		t.Fatalf("no user must have been created, got %d users", len(page.Users))
+	}
+}
+
+func TestCreateUserWithOversizedUsername_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"username": "` + strings.Repeat("k", 51) + `", "email": "klaasje.amandou@noname.com"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "username")
+}
+
+func TestPatchUserByUuidWithInvalidEmail_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	repositories, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"email": "Harry <harrier.dubois@rcm.org>"}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusUnprocessableEntity)
+	assertThatInvalidFieldsAreExpected(t, responseRecorder, "email")
+	user, _ := repositories.Users.GetByUuid(context.Background(), uuidHarry)
+	if user.Email != "harrier.dubois@rcm.org" {
+		t.Fatalf("user has an unexpected email %s", user.Email)
+	}
+}
+
+func TestPatchUserByUuidErasingFullName_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, uuidHarry, _ := prepareDbWithTestData(t)
+	_, router := core.SetupAppLayers(db, config.Config{})
+
+	body := `{"full_name": {"value": null}}`
+	req, _ := http.NewRequest(
+		http.MethodPatch, "/api/v1/users/"+uuidHarry.String(), strings.NewReader(body))
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, req)
+
+	assertThatResponseCodeIsExpected(t, responseRecorder, http.StatusNoContent)
+}
+
+func assertThatInvalidFieldsAreExpected(t *testing.T, responseRecorder *httptest.ResponseRecorder, fields ...string) {
+	t.Helper()
+
+	var response validationProblem
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(response.Errors) != len(fields) {
+		t.Fatalf("expected invalid fields %v, got %+v", fields, response.Errors)
+	}
+	for i, field := range fields {
+		if response.Errors[i].Field != field || response.Errors[i].Message == "" {
+			t.Fatalf("expected invalid fields %v, got %+v", fields, response.Errors)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"cruder/pkg/jwt"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// BearerAuth authenticates the requests with an Authorization: Bearer token and leaves the other ones to the fallback.
+// The principal is named after the subject of the token and granted the scopes of its claims.
+func BearerAuth(verifier *jwt.Verifier, scopeMapping map[string][]string, fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scheme, token, found := strings.Cut(ctx.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			fallback(ctx)
+			return
+		}
+
+		claims, err := verifier.Verify(ctx.Request.Context(), strings.TrimSpace(token))
+		if err == nil && utf8.RuneCountInString("jwt:"+claims.Subject) > model.MaxActorLength {
+			err = fmt.Errorf("%w: the subject is too long to be recorded as the actor", jwt.ErrInvalidToken)
+		}
+		if errors.Is(err, jwt.ErrInvalidToken) {
+			slog.Info("bearer token refused", "reason", err)
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		if err != nil {
+			slog.Error("failed to verify the bearer token", "error", err)
+			problem.AbortWithStatus(ctx, http.StatusInternalServerError, "failed to authenticate the request")
+			return
+		}
+
+		setPrincipal(ctx, model.Principal{
+			Name:   "jwt:" + claims.Subject,
+			Scopes: toScopes(claims.Scopes(), scopeMapping),
+			Roles:  claims.Roles,
+		})
+		ctx.Next()
+	}
+}
+
+// toScopes keeps the known scopes among the claim values, translated by the mapping if there is one.
+func toScopes(claimValues []string, scopeMapping map[string][]string) []string {
+	scopes := []string{}
+	for _, value := range claimValues {
+		candidates := []string{value}
+		if scopeMapping != nil {
+			candidates = scopeMapping[value]
+		}
+		for _, scope := range candidates {
+			if slices.Contains(model.AllScopes, scope) && !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes
+}
@@ -0,0 +1,10 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+func Deprecated() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", "true")
+		ctx.Next()
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"cruder/internal/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+const idempotentReplayedHeader = "Idempotent-Replayed"
+const maxIdempotencyKeyLength = 255
+
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type recordingResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingResponseWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency replays the stored response when a request is repeated under the same
+// Idempotency-Key. Server errors are not stored, so that such requests can be retried, and
+// a request that never records its outcome blocks the retries only until its lease is over.
+func Idempotency(repo repository.IdempotencyKeyRepository, ttl time.Duration, lease time.Duration) gin.HandlerFunc {
+	if lease <= 0 {
+		lease = ttl
+	}
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, "the Idempotency-Key header is too long")
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := model.IdempotencyRecord{
+			Actor:       ctx.GetString(ActorKey),
+			Key:         key,
+			Fingerprint: fingerprint(ctx.Request, body),
+		}
+		stored, claimed, err := repo.Claim(ctx.Request.Context(), record, ttl, lease)
+		if err != nil {
+			slog.Error("failed to claim the idempotency key", "error", err)
+			problem.AbortWithStatus(ctx, http.StatusInternalServerError, "failed to process the Idempotency-Key")
+			return
+		}
+		if !claimed {
+			replay(ctx, stored, record.Fingerprint)
+			return
+		}
+
+		writer := &recordingResponseWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		// the request context may be over already, yet the outcome must be recorded
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+		if writer.Status() >= http.StatusInternalServerError {
+			err = repo.Release(storeCtx, record.Actor, record.Key)
+		} else {
+			record.StatusCode = writer.Status()
+			record.Headers = map[string]string{}
+			for _, header := range replayedHeaders {
+				if value := writer.Header().Get(header); value != "" {
+					record.Headers[header] = value
+				}
+			}
+			record.Body = writer.body.Bytes()
+			err = repo.Complete(storeCtx, record)
+		}
+		if err != nil {
+			slog.Error("failed to store the idempotency key outcome", "error", err)
+		}
+	}
+}
+
+func replay(ctx *gin.Context, stored *model.IdempotencyRecord, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		problem.AbortWithStatus(ctx, http.StatusUnprocessableEntity,
+			"the Idempotency-Key has already been used for a different request")
+		return
+	}
+	if stored.StatusCode == 0 {
+		problem.AbortWithStatus(ctx, http.StatusConflict,
+			"a request with the same Idempotency-Key is still being processed")
+		return
+	}
+
+	for header, value := range stored.Headers {
+		ctx.Header(header, value)
+	}
+	ctx.Header(idempotentReplayedHeader, "true")
+	ctx.Status(stored.StatusCode)
+	if _, err := ctx.Writer.Write(stored.Body); err != nil {
+		slog.Error("failed to replay the stored response", "error", err)
+	}
+	ctx.Abort()
+}
+
+func fingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.Method + " " + request.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"cruder/internal/config"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SignatureHeader = "X-Signature"
+const SignatureKeyHeader = "X-Signature-Key"
+const SignatureTimestampHeader = "X-Signature-Timestamp"
+const SignatureNonceHeader = "X-Signature-Nonce"
+const maxNonceLength = 64
+const maxStoredNonces = 100000
+
+// maxSignedBodySize bounds the body held in memory to be hashed, well above any user or batch payload.
+const maxSignedBodySize = 1 << 20
+
+var errTooManySignedRequests = errors.New("too many signed requests, retry later")
+
+type signingKey struct {
+	secret    []byte
+	principal model.Principal
+}
+
+// nonceStore remembers the nonces of the signed requests as long as their timestamp is within the window.
+// Being in memory, it only protects against the replays reaching the same instance.
+type nonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// SignatureAuth authenticates the requests signed with the secret of a configured API key, and leaves the
+// unsigned ones to the fallback. The signature is the hex encoded HMAC-SHA256 of the method, the path with
+// the query, the timestamp, the nonce and the hex encoded SHA-256 of the body, joined by newlines.
+func SignatureAuth(apiKeys []config.APIKey, window time.Duration, fallback gin.HandlerFunc) gin.HandlerFunc {
+	keys := make(map[string]signingKey, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		keys[apiKey.Name] = signingKey{
+			secret:    []byte(apiKey.Key),
+			principal: model.Principal{Name: "api-key:" + apiKey.Name, Scopes: apiKey.Scopes, Roles: apiKey.Roles},
+		}
+	}
+	nonces := &nonceStore{nonces: map[string]time.Time{}}
+
+	return func(ctx *gin.Context) {
+		signature := ctx.GetHeader(SignatureHeader)
+		if signature == "" {
+			fallback(ctx)
+			return
+		}
+
+		keyName := ctx.GetHeader(SignatureKeyHeader)
+		timestampStr := ctx.GetHeader(SignatureTimestampHeader)
+		nonce := ctx.GetHeader(SignatureNonceHeader)
+		if keyName == "" || timestampStr == "" || nonce == "" || len(nonce) > maxNonceLength {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized,
+				"a signed request needs the X-Signature-Key, X-Signature-Timestamp and X-Signature-Nonce headers")
+			return
+		}
+
+		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+		now := time.Now()
+		if err != nil || now.Sub(time.Unix(timestamp, 0)).Abs() > window {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "the signature timestamp is outside the accepted window")
+			return
+		}
+
+		// an unknown key is refused before the body is read
+		key, known := keys[keyName]
+		if !known {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "invalid signature")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSignedBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.AbortWithStatus(ctx, http.StatusRequestEntityTooLarge, "the request body is too large")
+			return
+		}
+		if err != nil {
+			problem.AbortWithStatus(ctx, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := sign(key.secret, ctx.Request, timestampStr, nonce, body)
+		received, err := hex.DecodeString(signature)
+		if err != nil || !hmac.Equal(expected, received) {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "invalid signature")
+			return
+		}
+
+		// the nonce is valid as long as the timestamp is, a reused one being a replay
+		stored, err := nonces.claim(keyName+":"+nonce, time.Unix(timestamp, 0).Add(window), now)
+		if err != nil {
+			problem.AbortWithStatus(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if !stored {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "the signature nonce has already been used")
+			return
+		}
+
+		setPrincipal(ctx, key.principal)
+		ctx.Next()
+	}
+}
+
+func sign(secret []byte, request *http.Request, timestamp string, nonce string, body []byte) []byte {
+	bodyDigest := sha256.Sum256(body)
+	payload := strings.Join([]string{
+		request.Method,
+		request.URL.RequestURI(),
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyDigest[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// claim stores the nonce unless it is known already. A full store evicts the expired nonces first
+// and refuses new ones while none has expired, rather than forgetting nonces that could be replayed.
+func (s *nonceStore) claim(nonce string, expiresAt time.Time, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if storedExpiresAt, found := s.nonces[nonce]; found && now.Before(storedExpiresAt) {
+		return false, nil
+	}
+
+	if len(s.nonces) >= maxStoredNonces {
+		for storedNonce, storedExpiresAt := range s.nonces {
+			if !now.Before(storedExpiresAt) {
+				delete(s.nonces, storedNonce)
+			}
+		}
+		if len(s.nonces) >= maxStoredNonces {
+			return false, errTooManySignedRequests
+		}
+	}
+
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
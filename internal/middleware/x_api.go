@@ -1,26 +1,228 @@
 package middleware
 
 import (
+	"context"
+	"cruder/internal/config"
+	"cruder/internal/model"
+	"cruder/internal/problem"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 )
 
-func APIKeyAuth(correctKey string) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		if correctKey != "" {
-			apiKey := ctx.GetHeader("X-API-Key")
+const ActorKey = "actor"
+const PrincipalKey = "principal"
+const anonymousActor = "anonymous"
+const maxCachedAPIKeys = 1000
+
+// APIKeyAuthenticator resolves the keys kept in the database.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+	HasActiveKeys(ctx context.Context) (bool, error)
+}
+
+type configuredAPIKey struct {
+	digest            [sha256.Size]byte
+	principal         model.Principal
+	signatureRequired bool
+}
+
+type cachedAPIKey struct {
+	principal model.Principal
+	expiresAt time.Time
+}
 
-			if apiKey == "" {
-				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "X-API-Key header is missing"})
+// apiKeyCache remembers the successful lookups for a short while, so a revoked key may still be accepted
+// until its entry expires. Failed lookups are not remembered, so that random keys cannot fill it up.
+type apiKeyCache struct {
+	mu                  sync.Mutex
+	ttl                 time.Duration
+	keys                map[[sha256.Size]byte]cachedAPIKey
+	hasActiveKeys       bool
+	hasActiveKeysExpiry time.Time
+}
+
+// APIKeyAuth authenticates the X-API-Key header against the configured keys and the stored ones.
+// Without any key configured or stored every caller is let in as an anonymous principal with all the scopes,
+// unless anonymous callers are not allowed because of another way to authenticate.
+func APIKeyAuth(
+	legacyKey string,
+	apiKeys []config.APIKey,
+	authenticator APIKeyAuthenticator,
+	cacheTTL time.Duration,
+	anonymousAllowed bool,
+) gin.HandlerFunc {
+	configured := make([]configuredAPIKey, 0, len(apiKeys)+1)
+	for _, apiKey := range apiKeys {
+		configured = append(configured, configuredAPIKey{
+			digest:            sha256.Sum256([]byte(apiKey.Key)),
+			principal:         model.Principal{Name: "api-key:" + apiKey.Name, Scopes: apiKey.Scopes, Roles: apiKey.Roles},
+			signatureRequired: apiKey.SignatureRequired,
+		})
+	}
+	if legacyKey != "" {
+		keyFingerprint := sha256.Sum256([]byte(legacyKey))
+		configured = append(configured, configuredAPIKey{
+			digest: keyFingerprint,
+			principal: model.Principal{
+				Name:   "api-key:" + hex.EncodeToString(keyFingerprint[:4]),
+				Scopes: model.AllScopes,
+			},
+		})
+	}
+	cache := &apiKeyCache{ttl: cacheTTL, keys: map[[sha256.Size]byte]cachedAPIKey{}}
+
+	return func(ctx *gin.Context) {
+		if len(configured) == 0 && anonymousAllowed {
+			enabled, err := cache.hasActive(ctx.Request.Context(), authenticator)
+			if err != nil {
+				slog.Error("failed to look up the api keys", "error", err)
+				problem.AbortWithStatus(ctx, http.StatusInternalServerError, "failed to authenticate the request")
+				return
+			}
+			if !enabled {
+				setPrincipal(ctx, model.Principal{Name: anonymousActor, Scopes: model.UserScopes})
+				ctx.Next()
 				return
 			}
+		}
+
+		apiKey := ctx.GetHeader("X-API-Key")
+		if apiKey == "" {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "X-API-Key header is missing")
+			return
+		}
 
-			if apiKey != correctKey {
-				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid API key"})
+		key, found := findConfiguredKey(configured, apiKey)
+		if found && key.signatureRequired {
+			problem.AbortWithStatus(ctx, http.StatusUnauthorized, "the API key may only sign requests")
+			return
+		}
+		principal := key.principal
+		if !found {
+			var err error
+			if principal, found, err = cache.lookup(ctx.Request.Context(), authenticator, apiKey); err != nil {
+				slog.Error("failed to look up the api key", "error", err)
+				problem.AbortWithStatus(ctx, http.StatusInternalServerError, "failed to authenticate the request")
 				return
 			}
 		}
+		if !found {
+			problem.AbortWithStatus(ctx, http.StatusForbidden, "Invalid API key")
+			return
+		}
 
+		setPrincipal(ctx, principal)
 		ctx.Next()
 	}
 }
+
+// findConfiguredKey compares the digests in constant time and goes through every key,
+// so that the timing tells nothing about the configured keys.
+func findConfiguredKey(configured []configuredAPIKey, apiKey string) (configuredAPIKey, bool) {
+	digest := sha256.Sum256([]byte(apiKey))
+
+	var key configuredAPIKey
+	found := false
+	for _, candidate := range configured {
+		if subtle.ConstantTimeCompare(digest[:], candidate.digest[:]) == 1 {
+			key = candidate
+			found = true
+		}
+	}
+
+	return key, found
+}
+
+func (c *apiKeyCache) lookup(
+	ctx context.Context,
+	authenticator APIKeyAuthenticator,
+	apiKey string,
+) (model.Principal, bool, error) {
+	digest := sha256.Sum256([]byte(apiKey))
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, found := c.keys[digest]
+	c.mu.Unlock()
+	if found && now.Before(cached.expiresAt) {
+		return cached.principal, true, nil
+	}
+
+	key, err := authenticator.Authenticate(ctx, apiKey)
+	if err != nil || key == nil {
+		return model.Principal{}, false, err
+	}
+
+	expiresAt := now.Add(c.ttl)
+	if validUntil := key.ValidUntil(); !validUntil.IsZero() && validUntil.Before(expiresAt) {
+		expiresAt = validUntil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.keys) >= maxCachedAPIKeys {
+		for cachedDigest, entry := range c.keys {
+			if !now.Before(entry.expiresAt) {
+				delete(c.keys, cachedDigest)
+			}
+		}
+	}
+	if len(c.keys) < maxCachedAPIKeys {
+		c.keys[digest] = cachedAPIKey{principal: key.Principal(), expiresAt: expiresAt}
+	}
+
+	return key.Principal(), true, nil
+}
+
+func (c *apiKeyCache) hasActive(ctx context.Context, authenticator APIKeyAuthenticator) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if now.Before(c.hasActiveKeysExpiry) {
+		defer c.mu.Unlock()
+		return c.hasActiveKeys, nil
+	}
+	c.mu.Unlock()
+
+	hasActiveKeys, err := authenticator.HasActiveKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hasActiveKeys = hasActiveKeys
+	c.hasActiveKeysExpiry = now.Add(c.ttl)
+
+	return hasActiveKeys, nil
+}
+
+// RequireScope lets through the principals granted the scope only.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !GetPrincipal(ctx).HasScope(scope) {
+			problem.AbortWithStatus(ctx, http.StatusForbidden, "missing scope "+scope)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func GetPrincipal(ctx *gin.Context) model.Principal {
+	value, _ := ctx.Get(PrincipalKey)
+	principal, _ := value.(model.Principal)
+	return principal
+}
+
+func setPrincipal(ctx *gin.Context, principal model.Principal) {
+	ctx.Set(PrincipalKey, principal)
+	ctx.Set(ActorKey, principal.Name)
+	ctx.Request = ctx.Request.WithContext(model.WithPrincipal(ctx.Request.Context(), principal))
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+// APIKey is a stored key, of which only the salted hash is known.
+type APIKey struct {
+	ID         int
+	UUID       uuid.UUID
+	Name       string
+	Prefix     string
+	Salt       []byte
+	Hash       []byte
+	Scopes     []string
+	Roles      []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+// MintedAPIKey is a key that has just been minted, along with the raw key that is not stored anywhere.
+type MintedAPIKey struct {
+	APIKey
+	RawKey string
+}
+
+// ValidUntil is when the key stops being accepted, the zero time if it never does.
+func (k APIKey) ValidUntil() time.Time {
+	switch {
+	case !k.RevokedAt.Valid:
+		if k.ExpiresAt.Valid {
+			return k.ExpiresAt.Time
+		}
+		return time.Time{}
+	case k.ExpiresAt.Valid && k.ExpiresAt.Time.Before(k.RevokedAt.Time):
+		return k.ExpiresAt.Time
+	default:
+		return k.RevokedAt.Time
+	}
+}
+
+// Principal is named after the prefix as well, since the names are neither unique nor apart from the configured keys.
+func (k APIKey) Principal() Principal {
+	return Principal{Name: "api-key:" + k.Name + ":" + k.Prefix, Scopes: k.Scopes, Roles: k.Roles}
+}
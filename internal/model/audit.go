@@ -0,0 +1,91 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// AuditContext identifies who is behind a mutation and within which request.
+type AuditContext struct {
+	Actor     string
+	RequestID string
+}
+
+type FieldChange struct {
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+type AuditEntry struct {
+	ID        int
+	UserUUID  uuid.UUID
+	Action    string
+	Actor     string
+	RequestID string
+	Changes   map[string]FieldChange
+	CreatedAt time.Time
+}
+
+type AuditPage struct {
+	Entries    []AuditEntry
+	NextCursor string
+	PrevCursor string
+}
+
+// DiffUsers lists the audited fields that differ between two states of a user,
+// a nil state stands for a user that does not exist (yet or anymore).
+func DiffUsers(before *User, after *User) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	for field, value := range auditedFields {
+		var beforeValue, afterValue *string
+		if before != nil {
+			beforeValue = value(before)
+		}
+		if after != nil {
+			afterValue = value(after)
+		}
+
+		if !equalValues(beforeValue, afterValue) {
+			changes[field] = FieldChange{Before: beforeValue, After: afterValue}
+		}
+	}
+
+	return changes
+}
+
+var auditedFields = map[string]func(user *User) *string{
+	"username": func(user *User) *string {
+		return &user.Username
+	},
+	"email": func(user *User) *string {
+		return &user.Email
+	},
+	"full_name": func(user *User) *string {
+		if !user.FullName.Valid {
+			return nil
+		}
+		return &user.FullName.String
+	},
+	"deleted_at": func(user *User) *string {
+		if !user.DeletedAt.Valid {
+			return nil
+		}
+		value := user.DeletedAt.Time.UTC().Format(time.RFC3339Nano)
+		return &value
+	},
+}
+
+func equalValues(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
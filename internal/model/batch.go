@@ -0,0 +1,39 @@
+package model
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+)
+
+const UserOperationCreate = "create"
+const UserOperationPatch = "patch"
+const UserOperationDelete = "delete"
+
+type UserOperation struct {
+	Kind             string
+	UUID             uuid.UUID
+	ExpectedVersions []int
+	Create           CreateUserCommand
+	Patch            PatchUserCommand
+}
+
+// UserOperationResult is the outcome of a single operation of a batch, the user is nil for deletions.
+type UserOperationResult struct {
+	Kind string
+	User *User
+	Err  error
+}
+
+// BatchOperationError is the failure of the operation at Index that made an atomic batch roll back.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,26 @@
+package model
+
+type CreateUserCommand struct {
+	Username string
+	Email    string
+	FullName *string
+}
+
+type PatchUserCommand struct {
+	Username *string
+	Email    *string
+	FullName Optional[string]
+	// Tests must all hold for the current state of the user, otherwise the patch is not applied.
+	Tests []FieldTest
+}
+
+// FieldTest expects a user field to have the value, nil standing for null.
+type FieldTest struct {
+	Field string
+	Value *string
+}
+
+func (t FieldTest) Holds(user *User) bool {
+	value, known := auditedFields[t.Field]
+	return known && equalValues(value(user), t.Value)
+}
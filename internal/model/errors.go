@@ -0,0 +1,24 @@
+package model
+
+type ErrorKind int
+
+const (
+	ErrorKindNotFound ErrorKind = iota + 1
+	ErrorKindConflict
+	ErrorKindPreconditionFailed
+	ErrorKindInvalidInput
+)
+
+// BusinessError is an expected failure of a use case, its kind tells the callers how to report it.
+type BusinessError struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func NewBusinessError(kind ErrorKind, message string) *BusinessError {
+	return &BusinessError{Kind: kind, Message: message}
+}
+
+func (e *BusinessError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,12 @@
+package model
+
+// IdempotencyRecord is a request made under an Idempotency-Key, together with its response
+// once the request has been processed. StatusCode is zero while it is still being processed.
+type IdempotencyRecord struct {
+	Actor       string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Headers     map[string]string
+	Body        []byte
+}
@@ -0,0 +1,19 @@
+package model
+
+// Optional is a tri-state field of a partial update: absent, explicitly null, or holding a value.
+type Optional[T any] struct {
+	Present bool
+	Value   *T
+}
+
+func OptionalOf[T any](value T) Optional[T] {
+	return Optional[T]{Present: true, Value: &value}
+}
+
+func OptionalNull[T any]() Optional[T] {
+	return Optional[T]{Present: true}
+}
+
+func (o Optional[T]) IsNull() bool {
+	return o.Present && o.Value == nil
+}
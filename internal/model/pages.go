@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   []SortField
+}
+
+type UserPage struct {
+	Users      []User
+	NextCursor string
+	PrevCursor string
+}
+
+type UserFilter struct {
+	UsernamePrefix   *string
+	EmailDomain      *string
+	FullNameContains *string
+	CreatedAfter     *time.Time
+	CreatedBefore    *time.Time
+	Deleted          bool
+}
+
+// UserSearchResult is a user matching a search along with its relevance, from 0 to 1.
+type UserSearchResult struct {
+	User  User
+	Score float64
+}
+
+type UserSearchPage struct {
+	Results    []UserSearchResult
+	NextCursor string
+	PrevCursor string
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	PolicyActionRead    = "read"
+	PolicyActionCreate  = "create"
+	PolicyActionUpdate  = "update"
+	PolicyActionDelete  = "delete"
+	PolicyActionRestore = "restore"
+)
+
+var policyActions = []string{
+	PolicyActionRead, PolicyActionCreate, PolicyActionUpdate, PolicyActionDelete, PolicyActionRestore}
+
+// PolicyFields are the user fields the create and update actions are granted one by one.
+var PolicyFields = []string{"username", "email", "full_name"}
+
+// RolePolicy grants the actions to a role, Fields restricting the ones that its creations and updates may set.
+type RolePolicy struct {
+	Actions []string `json:"actions"`
+	Fields  []string `json:"fields"`
+}
+
+// UserPolicy grants the user management actions by role, the DefaultRoles being the ones of the principals without any.
+type UserPolicy struct {
+	Roles        map[string]RolePolicy `json:"roles"`
+	DefaultRoles []string              `json:"default_roles"`
+}
+
+// AccessDeniedError is an action refused by the policy. Fields lists the fields that may not be set
+// when the action itself is allowed, it is empty when the action is not allowed at all.
+type AccessDeniedError struct {
+	Action string
+	Fields []string
+}
+
+func (e *AccessDeniedError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("the %s action is not allowed", e.Action)
+	}
+	return fmt.Sprintf("the %s action is not allowed on %s", e.Action, strings.Join(e.Fields, ", "))
+}
+
+func ParseUserPolicy(data []byte) (*UserPolicy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var policy UserPolicy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, err
+	}
+
+	for role, rolePolicy := range policy.Roles {
+		for _, action := range rolePolicy.Actions {
+			if !slices.Contains(policyActions, action) {
+				return nil, fmt.Errorf("unknown action %q of role %s", action, role)
+			}
+		}
+		for _, field := range rolePolicy.Fields {
+			if !slices.Contains(PolicyFields, field) {
+				return nil, fmt.Errorf("unknown field %q of role %s", field, role)
+			}
+		}
+	}
+	for _, role := range policy.DefaultRoles {
+		if !policy.Defines(role) {
+			return nil, fmt.Errorf("undefined default role %s", role)
+		}
+	}
+
+	return &policy, nil
+}
+
+func (p *UserPolicy) Defines(role string) bool {
+	if p == nil {
+		return false
+	}
+	_, defined := p.Roles[role]
+	return defined
+}
+
+// Check allows the action if one of the roles grants it, and the fields if each is granted along with the action
+// by one of the roles.
+func (p *UserPolicy) Check(roles []string, action string, fields []string) error {
+	actionAllowed := false
+	allowedFields := map[string]bool{}
+	for _, role := range roles {
+		rolePolicy, defined := p.Roles[role]
+		if !defined || !slices.Contains(rolePolicy.Actions, action) {
+			continue
+		}
+		actionAllowed = true
+		for _, field := range rolePolicy.Fields {
+			allowedFields[field] = true
+		}
+	}
+	if !actionAllowed {
+		return &AccessDeniedError{Action: action}
+	}
+
+	var deniedFields []string
+	for _, field := range fields {
+		if !allowedFields[field] && !slices.Contains(deniedFields, field) {
+			deniedFields = append(deniedFields, field)
+		}
+	}
+	if len(deniedFields) > 0 {
+		return &AccessDeniedError{Action: action, Fields: deniedFields}
+	}
+
+	return nil
+}
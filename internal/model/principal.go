@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"slices"
+)
+
+const (
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopeUsersDelete = "users:delete"
+	// ScopeUsersAdmin grants listing and restoring the deleted users.
+	ScopeUsersAdmin = "users:admin"
+	// ScopeAPIKeysAdmin grants minting, listing, rotating and revoking the stored API keys.
+	ScopeAPIKeysAdmin = "api-keys:admin"
+)
+
+// UserScopes are the ones of the user management, which is all the anonymous caller gets while authentication is off.
+var UserScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeUsersDelete, ScopeUsersAdmin}
+
+var AllScopes = append(slices.Clone(UserScopes), ScopeAPIKeysAdmin)
+
+// MaxActorLength is the size of the actor columns, which every principal name has to fit in.
+const MaxActorLength = 255
+
+// Principal is the authenticated caller, its name is recorded as the actor of the mutations it makes.
+// Its roles are the ones the UserPolicy knows.
+type Principal struct {
+	Name   string
+	Scopes []string
+	Roles  []string
+}
+
+type principalContextKey struct{}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, found := ctx.Value(principalContextKey{}).(Principal)
+	return principal, found
+}
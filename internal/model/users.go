@@ -3,12 +3,17 @@ package model
 import (
 	"database/sql"
 	"github.com/google/uuid"
+	"time"
 )
 
 type User struct {
-	ID       int
-	UUID     uuid.UUID
-	Username string
-	Email    string
-	FullName sql.NullString
+	ID        int
+	UUID      uuid.UUID
+	Username  string
+	Email     string
+	FullName  sql.NullString
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt sql.NullTime
+	Version   int
 }
@@ -0,0 +1,54 @@
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+const requestIDHeader = "X-Request-ID"
+
+// Details is an RFC 9457 problem details object.
+type Details struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func New(status int, detail string) Details {
+	return Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (d Details) WithErrors(errors []FieldError) Details {
+	d.Errors = errors
+	return d
+}
+
+// Abort writes the problem as the response, with the request path as its instance and
+// the request ID already put on the response, and stops the remaining handlers.
+func Abort(ctx *gin.Context, details Details) {
+	details.Instance = ctx.Request.URL.Path
+	details.RequestID = ctx.Writer.Header().Get(requestIDHeader)
+
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(details.Status, details)
+}
+
+func AbortWithStatus(ctx *gin.Context, status int, detail string) {
+	Abort(ctx, New(status, detail))
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key model.APIKey) (*model.APIKey, error)
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+	GetActiveByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	HasActive(ctx context.Context) (bool, error)
+	TouchLastUsed(ctx context.Context, id int) error
+	// Rotate stores the replacement with the name, scopes, roles and expiry of the active key, which is revoked
+	// once the grace period is over.
+	Rotate(ctx context.Context, uuid uuid.UUID, replacement model.APIKey, gracePeriod time.Duration) (*model.APIKey, error)
+	Revoke(ctx context.Context, uuid uuid.UUID) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+var BusinessErrNoAPIKeys = model.NewBusinessError(model.ErrorKindNotFound, "api key not found")
+
+const apiKeyColumns = `id, uuid, name, prefix, salt, hash, scopes, roles, created_at, expires_at, last_used_at, revoked_at`
+
+const activeAPIKeyCondition = `(revoked_at IS NULL OR revoked_at > now()) AND (expires_at IS NULL OR expires_at > now())`
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key model.APIKey) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_keys (name, prefix, salt, hash, scopes, roles, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		key.Name, key.Prefix, key.Salt, key.Hash, pq.Array(key.Scopes), pq.Array(key.Roles), key.ExpiresAt))
+}
+
+func (r *apiKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) GetActiveByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1 AND `+activeAPIKeyCondition,
+		prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoAPIKeys
+	}
+
+	return key, err
+}
+
+func (r *apiKeyRepository) HasActive(ctx context.Context) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM api_keys WHERE `+activeAPIKeyCondition+`)`).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1`, id)
+	return err
+}
+
+func (r *apiKeyRepository) Rotate(
+	ctx context.Context,
+	uuid uuid.UUID,
+	replacement model.APIKey,
+	gracePeriod time.Duration,
+) (*model.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(
+		ctx,
+		`WITH rotated AS (
+			UPDATE api_keys
+			SET revoked_at = LEAST(COALESCE(revoked_at, 'infinity'), now() + make_interval(secs => $5))
+			WHERE uuid = $1 AND `+activeAPIKeyCondition+`
+			RETURNING name, scopes, roles, expires_at
+		)
+		INSERT INTO api_keys (name, prefix, salt, hash, scopes, roles, expires_at)
+		SELECT name, $2, $3, $4, scopes, roles, expires_at FROM rotated
+		RETURNING `+apiKeyColumns,
+		uuid, replacement.Prefix, replacement.Salt, replacement.Hash, gracePeriod.Seconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoAPIKeys
+	}
+
+	return key, err
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, uuid uuid.UUID) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE api_keys SET revoked_at = now() WHERE uuid = $1 AND `+activeAPIKeyCondition,
+		uuid)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return BusinessErrNoAPIKeys
+	}
+
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := row.Scan(
+		&key.ID, &key.UUID, &key.Name, &key.Prefix, &key.Salt, &key.Hash, pq.Array(&key.Scopes), pq.Array(&key.Roles),
+		&key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+)
+
+type AuditLogRepository interface {
+	GetByUserUuid(ctx context.Context, uuid uuid.UUID, pageRequest model.PageRequest) (*model.AuditPage, error)
+}
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+const auditLogColumns = `id, user_uuid, action, actor, request_id, changes, created_at`
+
+// history pages go from the newest entry to the oldest one
+const auditLogSort = "-id"
+
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) GetByUserUuid(ctx context.Context, uuid uuid.UUID, pageRequest model.PageRequest) (*model.AuditPage, error) {
+	position, err := parseCursor(pageRequest.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if position != nil && (position.Sort != auditLogSort || len(position.Values) != 0) {
+		return nil, BusinessErrInvalidCursor
+	}
+
+	backward := position != nil && position.Backward
+	query := `SELECT ` + auditLogColumns + ` FROM user_audit_log WHERE user_uuid = $1`
+	args := []interface{}{uuid}
+	switch {
+	case position == nil:
+		query += ` ORDER BY id DESC LIMIT $2`
+	case backward:
+		query += ` AND id > $3 ORDER BY id ASC LIMIT $2`
+		args = append(args, position.ID)
+	default:
+		query += ` AND id < $3 ORDER BY id DESC LIMIT $2`
+		args = append(args, position.ID)
+	}
+	args = append(args, pageRequest.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]model.AuditEntry, 0, pageRequest.Limit+1)
+	for rows.Next() {
+		var entry model.AuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.UserUUID, &entry.Action, &entry.Actor, &entry.RequestID,
+			&changes, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 && position == nil {
+		if err := r.ensureUserEverExisted(ctx, uuid); err != nil {
+			return nil, err
+		}
+	}
+
+	return toAuditPage(entries, pageRequest.Limit, position), nil
+}
+
+func (r *auditLogRepository) ensureUserEverExisted(ctx context.Context, uuid uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE uuid = $1)`,
+		uuid).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return BusinessErrNoUsers
+	}
+
+	return nil
+}
+
+func toAuditPage(entries []model.AuditEntry, limit int, position *cursor) *model.AuditPage {
+	page := &model.AuditPage{}
+	page.Entries, page.NextCursor, page.PrevCursor = paginate(entries, limit, position,
+		func(entry *model.AuditEntry, backward bool) string {
+			return cursor{Sort: auditLogSort, ID: entry.ID, Backward: backward}.encode()
+		})
+
+	return page
+}
+
+func insertAuditEntry(
+	ctx context.Context,
+	executor dbExecutor,
+	audit model.AuditContext,
+	action string,
+	before *model.User,
+	after *model.User,
+) error {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+
+	changes, err := json.Marshal(model.DiffUsers(before, after))
+	if err != nil {
+		return err
+	}
+
+	_, err = executor.ExecContext(
+		ctx,
+		`INSERT INTO user_audit_log (user_uuid, action, actor, request_id, changes) VALUES ($1, $2, $3, $4, $5)`,
+		subject.UUID, action, audit.Actor, audit.RequestID, string(changes))
+
+	return err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"cruder/internal/model"
+	"fmt"
+	"strings"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (q *keysetQuery) filter(filter model.UserFilter) {
+	if filter.Deleted {
+		q.whereParts = append(q.whereParts, "deleted_at IS NOT NULL")
+	} else {
+		q.whereParts = append(q.whereParts, "deleted_at IS NULL")
+	}
+
+	if filter.UsernamePrefix != nil {
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("username LIKE %s", q.placeholder(likeEscaper.Replace(strings.ToLower(*filter.UsernamePrefix))+"%")))
+	}
+
+	if filter.EmailDomain != nil {
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("email ILIKE %s", q.placeholder("%@"+likeEscaper.Replace(*filter.EmailDomain))))
+	}
+
+	if filter.FullNameContains != nil {
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("full_name ILIKE %s", q.placeholder("%"+likeEscaper.Replace(*filter.FullNameContains)+"%")))
+	}
+
+	if filter.CreatedAfter != nil {
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("created_at > %s", q.placeholder(*filter.CreatedAfter)))
+	}
+
+	if filter.CreatedBefore != nil {
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("created_at < %s", q.placeholder(*filter.CreatedBefore)))
+	}
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type IdempotencyKeyRepository interface {
+	// Claim stores the record as being processed for the lease, unless a live record exists for the same
+	// actor and key, which is returned instead. The returned flag tells if the claim succeeded.
+	Claim(
+		ctx context.Context, record model.IdempotencyRecord, ttl time.Duration, lease time.Duration,
+	) (*model.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record model.IdempotencyRecord) error
+	Release(ctx context.Context, actor string, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyKeyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyKeyRepository(db *sql.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+func (r *idempotencyKeyRepository) Claim(
+	ctx context.Context,
+	record model.IdempotencyRecord,
+	ttl time.Duration,
+	lease time.Duration,
+) (*model.IdempotencyRecord, bool, error) {
+	for {
+		// an expired record, or a claim whose lease is over, is taken over as if it never existed
+		var claimed bool
+		err := r.db.QueryRowContext(
+			ctx,
+			`INSERT INTO idempotency_keys (actor, idempotency_key, fingerprint, expires_at, locked_until)
+			VALUES ($1, $2, $3, now() + make_interval(secs => $4), now() + make_interval(secs => $5))
+			ON CONFLICT (actor, idempotency_key) DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, headers = NULL, body = NULL,
+				created_at = now(), expires_at = EXCLUDED.expires_at, locked_until = EXCLUDED.locked_until
+			WHERE idempotency_keys.expires_at < now()
+				OR (idempotency_keys.status_code IS NULL
+					AND (idempotency_keys.locked_until IS NULL OR idempotency_keys.locked_until < now()))
+			RETURNING true`,
+			record.Actor, record.Key, record.Fingerprint, ttl.Seconds(), lease.Seconds()).Scan(&claimed)
+		if err == nil {
+			return &record, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, err
+		}
+
+		existing, err := r.get(ctx, record.Actor, record.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			// released or its lease over in the meantime, so it can be claimed again
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return existing, false, nil
+	}
+}
+
+func (r *idempotencyKeyRepository) get(ctx context.Context, actor string, key string) (*model.IdempotencyRecord, error) {
+	record := model.IdempotencyRecord{Actor: actor, Key: key}
+	var statusCode sql.NullInt64
+	var headers sql.NullString
+	if err := r.db.QueryRowContext(
+		ctx,
+		`SELECT fingerprint, status_code, headers, body FROM idempotency_keys
+		WHERE actor = $1 AND idempotency_key = $2 AND expires_at >= now()
+			AND (status_code IS NOT NULL OR locked_until >= now())`,
+		actor, key).Scan(&record.Fingerprint, &statusCode, &headers, &record.Body); err != nil {
+		return nil, err
+	}
+
+	record.StatusCode = int(statusCode.Int64)
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &record.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	return &record, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, record model.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5, locked_until = NULL
+		WHERE actor = $1 AND idempotency_key = $2`,
+		record.Actor, record.Key, record.StatusCode, string(headers), record.Body)
+
+	return err
+}
+
+func (r *idempotencyKeyRepository) Release(ctx context.Context, actor string, key string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys WHERE actor = $1 AND idempotency_key = $2 AND status_code IS NULL`,
+		actor, key)
+
+	return err
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"cruder/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type sortKey struct {
+	column     string
+	descending bool
+	nullable   bool
+	value      func(user *model.User) *string
+}
+
+// cursor is the decoded form of the opaque page token handed out to clients.
+// Values holds the sort key values of the boundary row, ID is the tiebreaker.
+// Sort pins the ordering the cursor was issued for.
+type cursor struct {
+	Sort     string    `json:"s"`
+	Values   []*string `json:"v"`
+	ID       int       `json:"id"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+var sortableColumns = map[string]func(user *model.User) *string{
+	"username": func(user *model.User) *string {
+		return &user.Username
+	},
+	"email": func(user *model.User) *string {
+		return &user.Email
+	},
+	"full_name": func(user *model.User) *string {
+		if !user.FullName.Valid {
+			return nil
+		}
+		return &user.FullName.String
+	},
+	"created_at": func(user *model.User) *string {
+		value := user.CreatedAt.Format(time.RFC3339Nano)
+		return &value
+	},
+}
+
+// nullableColumns are the sortable columns that may hold NULL, the others get plain comparisons
+// so that their indexes can bound the seek.
+var nullableColumns = map[string]bool{"full_name": true}
+
+var defaultSort = []model.SortField{{Field: "full_name"}}
+
+func resolveSortKeys(fields []model.SortField) ([]sortKey, error) {
+	if len(fields) == 0 {
+		fields = defaultSort
+	}
+
+	keys := make([]sortKey, 0, len(fields))
+	seen := map[string]bool{}
+	for _, field := range fields {
+		value, sortable := sortableColumns[field.Field]
+		if !sortable || seen[field.Field] {
+			return nil, BusinessErrInvalidSort
+		}
+		seen[field.Field] = true
+		keys = append(keys, sortKey{
+			column:     field.Field,
+			descending: field.Descending,
+			nullable:   nullableColumns[field.Field],
+			value:      value,
+		})
+	}
+
+	return keys, nil
+}
+
+func sortSpec(keys []sortKey) string {
+	spec := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.descending {
+			spec = append(spec, "-"+key.column)
+		} else {
+			spec = append(spec, key.column)
+		}
+	}
+
+	return strings.Join(spec, ",")
+}
+
+func encodeCursor(keys []sortKey, user *model.User, backward bool) string {
+	position := cursor{
+		Sort:     sortSpec(keys),
+		Values:   make([]*string, 0, len(keys)),
+		ID:       user.ID,
+		Backward: backward,
+	}
+	for _, key := range keys {
+		position.Values = append(position.Values, key.value(user))
+	}
+
+	return position.encode()
+}
+
+func (c cursor) encode() string {
+	serialized, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(serialized)
+}
+
+func decodeCursor(keys []sortKey, token string) (*cursor, error) {
+	position, err := parseCursor(token)
+	if err != nil || position == nil {
+		return nil, err
+	}
+	if position.Sort != sortSpec(keys) || len(position.Values) != len(keys) {
+		return nil, BusinessErrInvalidCursor
+	}
+	for i, key := range keys {
+		if position.Values[i] == nil && !key.nullable {
+			return nil, BusinessErrInvalidCursor
+		}
+	}
+
+	return position, nil
+}
+
+func parseCursor(token string) (*cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	serialized, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, BusinessErrInvalidCursor
+	}
+
+	var position cursor
+	if err := json.Unmarshal(serialized, &position); err != nil {
+		return nil, BusinessErrInvalidCursor
+	}
+
+	return &position, nil
+}
+
+type keysetQuery struct {
+	whereParts []string
+	args       []interface{}
+}
+
+func (q *keysetQuery) placeholder(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *keysetQuery) where() string {
+	if len(q.whereParts) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.whereParts, " AND ")
+}
+
+// seek adds the predicate selecting rows strictly after the cursor position in the
+// direction of travel. NULLs are sorted last whatever the key direction is, so walking
+// backwards meets them first. The id tiebreaker makes the ordering total.
+func (q *keysetQuery) seek(keys []sortKey, position *cursor) {
+	if position == nil {
+		return
+	}
+
+	// The disjunction below cannot be matched to an index, this redundant range on the first key can,
+	// unless the NULLs that follow the cursor have to be kept.
+	if first := keys[0]; position.Values[0] != nil && (!first.nullable || position.Backward) {
+		comparison := ">="
+		if first.descending != position.Backward {
+			comparison = "<="
+		}
+		q.whereParts = append(q.whereParts,
+			fmt.Sprintf("%s %s %s", first.column, comparison, q.placeholder(*position.Values[0])))
+	}
+
+	alternatives := []string{}
+	equalities := []string{}
+	for i, key := range keys {
+		value := position.Values[i]
+		var valuePlaceholder string
+		if value != nil {
+			valuePlaceholder = q.placeholder(*value)
+		}
+
+		if after := afterCondition(key, value, valuePlaceholder, position.Backward); after != "" {
+			alternatives = append(alternatives, conjunction(append(equalities, after)))
+		}
+
+		if value == nil {
+			equalities = append(equalities, key.column+" IS NULL")
+		} else {
+			equalities = append(equalities, fmt.Sprintf("%s = %s", key.column, valuePlaceholder))
+		}
+	}
+
+	idComparison := ">"
+	if position.Backward {
+		idComparison = "<"
+	}
+	alternatives = append(alternatives, conjunction(append(equalities,
+		fmt.Sprintf("id %s %s", idComparison, q.placeholder(position.ID)))))
+
+	q.whereParts = append(q.whereParts, "("+strings.Join(alternatives, " OR ")+")")
+}
+
+func afterCondition(key sortKey, value *string, valuePlaceholder string, backward bool) string {
+	descending := key.descending != backward
+	nullsLast := !backward
+
+	if value == nil {
+		if nullsLast {
+			return ""
+		}
+		return key.column + " IS NOT NULL"
+	}
+
+	comparison := ">"
+	if descending {
+		comparison = "<"
+	}
+	if nullsLast && key.nullable {
+		return fmt.Sprintf("(%s %s %s OR %s IS NULL)", key.column, comparison, valuePlaceholder, key.column)
+	}
+	return fmt.Sprintf("%s %s %s", key.column, comparison, valuePlaceholder)
+}
+
+func conjunction(parts []string) string {
+	return "(" + strings.Join(parts, " AND ") + ")"
+}
+
+func orderBy(keys []sortKey, backward bool) string {
+	orderParts := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		descending := key.descending != backward
+		direction := "ASC"
+		if descending {
+			direction = "DESC"
+		}
+		if !key.nullable {
+			// Leaving out the NULLS clause lets a descending walk scan the ascending index backwards.
+			orderParts = append(orderParts, fmt.Sprintf("%s %s", key.column, direction))
+			continue
+		}
+		nulls := "NULLS LAST"
+		if backward {
+			nulls = "NULLS FIRST"
+		}
+		orderParts = append(orderParts, fmt.Sprintf("%s %s %s", key.column, direction, nulls))
+	}
+
+	idDirection := "ASC"
+	if backward {
+		idDirection = "DESC"
+	}
+	orderParts = append(orderParts, "id "+idDirection)
+
+	return " ORDER BY " + strings.Join(orderParts, ", ")
+}
+
+func toPage(keys []sortKey, users []model.User, limit int, position *cursor) *model.UserPage {
+	page := &model.UserPage{}
+	page.Users, page.NextCursor, page.PrevCursor = paginate(users, limit, position,
+		func(user *model.User, backward bool) string {
+			return encodeCursor(keys, user, backward)
+		})
+
+	return page
+}
+
+// paginate drops the look-ahead item, restores the natural order of a backward page and encodes
+// the cursors of the items at its edges.
+func paginate[T any](
+	items []T, limit int, position *cursor, encode func(item *T, backward bool) string,
+) (page []T, nextCursor string, prevCursor string) {
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	backward := position != nil && position.Backward
+	if backward {
+		slices.Reverse(items)
+	}
+
+	if len(items) == 0 {
+		return items, "", ""
+	}
+
+	if hasMore || backward {
+		nextCursor = encode(&items[len(items)-1], false)
+	}
+	if (hasMore && backward) || (position != nil && !backward) {
+		prevCursor = encode(&items[0], true)
+	}
+
+	return items, nextCursor, prevCursor
+}
@@ -3,11 +3,17 @@ package repository
 import "database/sql"
 
 type Repository struct {
-	Users UserRepository
+	Users           UserRepository
+	AuditLog        AuditLogRepository
+	IdempotencyKeys IdempotencyKeyRepository
+	APIKeys         APIKeyRepository
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{
-		Users: NewUserRepository(db),
+		Users:           NewUserRepository(db),
+		AuditLog:        NewAuditLogRepository(db),
+		IdempotencyKeys: NewIdempotencyKeyRepository(db),
+		APIKeys:         NewAPIKeyRepository(db),
 	}
 }
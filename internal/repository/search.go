@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"strconv"
+)
+
+// search pages go from the most relevant user to the least relevant one
+const userSearchSort = "-score"
+
+// userSearchScore is the best word similarity between the query and any of the searched fields.
+// The <% operator keeps the users above pg_trgm.word_similarity_threshold and is backed by the trigram indexes.
+const userSearchQuery = `
+    SELECT ` + userColumns + `, score FROM (
+        SELECT ` + userColumns + `,
+            GREATEST(
+                word_similarity($1, username),
+                word_similarity($1, email),
+                word_similarity($1, coalesce(full_name, ''))
+            )::float8 AS score
+        FROM users
+        WHERE deleted_at IS NULL AND ($1 <% username OR $1 <% email OR $1 <% full_name)
+    ) AS ranked`
+
+func (r *userRepository) Search(ctx context.Context, text string, pageRequest model.PageRequest) (*model.UserSearchPage, error) {
+	position, score, err := decodeSearchCursor(pageRequest.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	backward := position != nil && position.Backward
+	query := userSearchQuery
+	args := []interface{}{text, pageRequest.Limit + 1}
+	switch {
+	case position == nil:
+		query += ` ORDER BY score DESC, id ASC LIMIT $2`
+	case backward:
+		query += ` WHERE score > $3 OR (score = $3 AND id < $4) ORDER BY score ASC, id DESC LIMIT $2`
+		args = append(args, score, position.ID)
+	default:
+		query += ` WHERE score < $3 OR (score = $3 AND id > $4) ORDER BY score DESC, id ASC LIMIT $2`
+		args = append(args, score, position.ID)
+	}
+
+	rows, err := r.executor().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.UserSearchResult, 0, pageRequest.Limit+1)
+	for rows.Next() {
+		var result model.UserSearchResult
+		user := &result.User
+		if err := rows.Scan(
+			&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName,
+			&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.Version, &result.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return toSearchPage(results, pageRequest.Limit, position), nil
+}
+
+func decodeSearchCursor(token string) (*cursor, float64, error) {
+	position, err := parseCursor(token)
+	if err != nil || position == nil {
+		return nil, 0, err
+	}
+	if position.Sort != userSearchSort || len(position.Values) != 1 || position.Values[0] == nil {
+		return nil, 0, BusinessErrInvalidCursor
+	}
+
+	score, err := strconv.ParseFloat(*position.Values[0], 64)
+	if err != nil {
+		return nil, 0, BusinessErrInvalidCursor
+	}
+
+	return position, score, nil
+}
+
+func encodeSearchCursor(result *model.UserSearchResult, backward bool) string {
+	score := strconv.FormatFloat(result.Score, 'g', -1, 64)
+	return cursor{Sort: userSearchSort, Values: []*string{&score}, ID: result.User.ID, Backward: backward}.encode()
+}
+
+func toSearchPage(results []model.UserSearchResult, limit int, position *cursor) *model.UserSearchPage {
+	page := &model.UserSearchPage{}
+	page.Results, page.NextCursor, page.PrevCursor = paginate(results, limit, position, encodeSearchCursor)
+
+	return page
+}
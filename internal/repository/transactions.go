@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *userRepository) executor() dbExecutor {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+func (r *userRepository) WithinTransaction(ctx context.Context, fn func(txRepo UserRepository) error) error {
+	return r.inTransaction(ctx, func(txRepo *userRepository) error {
+		return fn(txRepo)
+	})
+}
+
+// inTransaction runs fn against a repository bound to a transaction, joining the
+// transaction the repository is already bound to, if any.
+func (r *userRepository) inTransaction(ctx context.Context, fn func(txRepo *userRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&userRepository{db: r.db, tx: tx}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -2,116 +2,223 @@ package repository
 
 import (
 	"context"
-	"cruder/internal/controller/dto"
 	"cruder/internal/model"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"slices"
 	"strings"
+	"time"
 )
 
 type UserRepository interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	DeleteByUuid(uuid uuid.UUID) error
-	PartiallyUpdateByUUID(uuid uuid.UUID, patch dto.UserPatch) error
-	Create(user dto.UserCreate) (*model.User, error)
+	GetAll(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	// Search ranks the live users by the similarity of their username, email or full name to the text.
+	Search(ctx context.Context, text string, pageRequest model.PageRequest) (*model.UserSearchPage, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUuid(ctx context.Context, uuid uuid.UUID) (*model.User, error)
+	DeleteByUuid(ctx context.Context, uuid uuid.UUID, expectedVersions []int, audit model.AuditContext) error
+	RestoreByUuid(ctx context.Context, uuid uuid.UUID, audit model.AuditContext) (*model.User, error)
+	PurgeDeletedBefore(ctx context.Context, deletedBefore time.Time, audit model.AuditContext) (int64, error)
+	PartiallyUpdateByUUID(
+		ctx context.Context, uuid uuid.UUID, patch model.PatchUserCommand, expectedVersions []int, audit model.AuditContext) (*model.User, error)
+	ReplaceByUuid(
+		ctx context.Context, uuid uuid.UUID, user model.CreateUserCommand, expectedVersions []int, audit model.AuditContext,
+	) (*model.User, error)
+	Create(ctx context.Context, user model.CreateUserCommand, audit model.AuditContext) (*model.User, error)
+	// UpsertByUsername creates the user or updates the live one with the same username,
+	// the returned flag tells if the user has been created.
+	UpsertByUsername(ctx context.Context, user model.CreateUserCommand, audit model.AuditContext) (*model.User, bool, error)
+	// WithinTransaction runs fn against a repository whose methods all share a single transaction.
+	WithinTransaction(ctx context.Context, fn func(txRepo UserRepository) error) error
 }
 
 type userRepository struct {
 	db *sql.DB
+	tx *sql.Tx
 }
 
-var BusinessErrNoUsers = errors.New("users not found")
-var BusinessErrUsernameTaken = errors.New("the username is already taken")
-var BusinessErrEmailTaken = errors.New("the email is already in use")
-var BusinessErrUnknownConflict = errors.New("unknown conflict")
+var BusinessErrNoUsers = model.NewBusinessError(model.ErrorKindNotFound, "users not found")
+var BusinessErrUsernameTaken = model.NewBusinessError(model.ErrorKindConflict, "the username is already taken")
+var BusinessErrEmailTaken = model.NewBusinessError(model.ErrorKindConflict, "the email is already in use")
+var BusinessErrUnknownConflict = model.NewBusinessError(model.ErrorKindConflict, "unknown conflict")
+var BusinessErrVersionMismatch = model.NewBusinessError(
+	model.ErrorKindPreconditionFailed, "the user has been modified in the meantime")
+var BusinessErrPatchTestFailed = model.NewBusinessError(model.ErrorKindConflict, "the patch test has failed")
+var BusinessErrInvalidCursor = model.NewBusinessError(model.ErrorKindInvalidInput, "invalid cursor")
+var BusinessErrInvalidSort = model.NewBusinessError(model.ErrorKindInvalidInput, "invalid sort")
 
 const uniqueConstraintViolationCode = "23505"
 
+const userColumns = `id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) GetAll() ([]model.User, error) {
-	rows, err := r.db.QueryContext(
-		context.Background(),
-		`SELECT id, uuid, username, email, full_name FROM users ORDER BY full_name`)
+func (r *userRepository) GetAll(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error) {
+	keys, err := resolveSortKeys(pageRequest.Sort)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	position, err := decodeCursor(keys, pageRequest.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var query keysetQuery
+	query.filter(filter)
+	query.seek(keys, position)
+	backward := position != nil && position.Backward
+
+	// #nosec G202 -- only whitelisted column names are concatenated, values are placeholders
+	statement := `SELECT ` + userColumns + ` FROM users` +
+		query.where() +
+		orderBy(keys, backward) +
+		" LIMIT " + query.placeholder(pageRequest.Limit+1)
 
-	var usersCount int
-	err = r.db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM users").Scan(&usersCount)
+	users, err := scanUsers(r.executor().QueryContext(ctx, statement, query.args...))
 	if err != nil {
 		return nil, err
 	}
 
-	allUsers := make([]model.User, 0, usersCount)
-	for rows.Next() {
-		var user model.User
-		if err := rows.Scan(&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName); err != nil {
-			return nil, err
-		}
-		allUsers = append(allUsers, user)
+	return toPage(keys, users, pageRequest.Limit, position), nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE lower(username) = lower($1) AND deleted_at IS NULL`,
+		username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return user, err
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`,
+		email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
 	}
 
-	return allUsers, nil
+	return user, err
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
-	var user model.User
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE id = $1 AND deleted_at IS NULL`,
+		id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
+	}
 
-	if err := r.db.QueryRowContext(
-		context.Background(),
-		`SELECT id, uuid, username, email, full_name FROM users WHERE username = $1`,
-		username).Scan(&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, BusinessErrNoUsers
-		}
-		return nil, err
+	return user, err
+}
+
+func (r *userRepository) GetByUuid(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE uuid = $1 AND deleted_at IS NULL`,
+		uuid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
 	}
 
-	return &user, nil
+	return user, err
 }
 
-func (r *userRepository) GetByID(id int64) (*model.User, error) {
-	var user model.User
+func (r *userRepository) DeleteByUuid(ctx context.Context, uuid uuid.UUID, expectedVersions []int, audit model.AuditContext) error {
+	return r.inTransaction(ctx, func(txRepo *userRepository) error {
+		before, err := txRepo.lockByUuid(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+		if !versionMatches(before.Version, expectedVersions) {
+			return BusinessErrVersionMismatch
+		}
 
-	if err := r.db.QueryRowContext(
-		context.Background(),
-		`SELECT id, uuid, username, email, full_name FROM users WHERE id = $1`,
-		id).Scan(&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, BusinessErrNoUsers
+		after, err := scanUser(txRepo.executor().QueryRowContext(
+			ctx,
+			`UPDATE users SET deleted_at = now(), version = version + 1 WHERE id = $1 RETURNING `+userColumns,
+			before.ID))
+		if err != nil {
+			return err
 		}
-		return nil, err
-	}
 
-	return &user, nil
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionDelete, before, after)
+	})
 }
 
-func (r *userRepository) DeleteByUuid(uuid uuid.UUID) error {
-	result, err := r.db.ExecContext(context.Background(), `DELETE FROM users WHERE uuid = $1`, uuid)
+func (r *userRepository) RestoreByUuid(ctx context.Context, uuid uuid.UUID, audit model.AuditContext) (*model.User, error) {
+	var restoredUser *model.User
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		before, err := txRepo.lockByUuid(ctx, uuid, true)
+		if err != nil {
+			return err
+		}
+
+		restoredUser, err = scanUser(txRepo.executor().QueryRowContext(
+			ctx,
+			`UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = $1 RETURNING `+userColumns,
+			before.ID))
+		if err != nil {
+			return processConstraintViolations(err)
+		}
+
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionRestore, before, restoredUser)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return ensureSomeRowsAffected(result)
+	return restoredUser, nil
+}
+
+func (r *userRepository) PurgeDeletedBefore(ctx context.Context, deletedBefore time.Time, audit model.AuditContext) (int64, error) {
+	var purgedCount int64
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		purgedUsers, err := scanUsers(txRepo.executor().QueryContext(
+			ctx,
+			`DELETE FROM users WHERE deleted_at < $1 RETURNING `+userColumns,
+			deletedBefore))
+		if err != nil {
+			return err
+		}
+
+		for _, user := range purgedUsers {
+			if err := insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionPurge, &user, nil); err != nil {
+				return err
+			}
+		}
+		purgedCount = int64(len(purgedUsers))
+
+		return nil
+	})
+
+	return purgedCount, err
 }
 
 func (r *userRepository) PartiallyUpdateByUUID(
+	ctx context.Context,
 	uuid uuid.UUID,
-	patch dto.UserPatch,
-) error {
+	patch model.PatchUserCommand,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
 	setParts := []string{}
 	args := []interface{}{}
 	sqlPlaceholderIndex := 1
@@ -128,9 +235,9 @@ func (r *userRepository) PartiallyUpdateByUUID(
 		sqlPlaceholderIndex++
 	}
 
-	if patch.FullName != nil {
+	if patch.FullName.Present {
 		setParts = append(setParts, fmt.Sprintf("full_name = $%d", sqlPlaceholderIndex))
-		if patch.FullName.Value == nil {
+		if patch.FullName.IsNull() {
 			args = append(args, nil)
 		} else {
 			args = append(args, patch.FullName.Value)
@@ -138,53 +245,242 @@ func (r *userRepository) PartiallyUpdateByUUID(
 		sqlPlaceholderIndex++
 	}
 
-	if len(setParts) == 0 {
-		return nil
+	var updatedUser *model.User
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		before, err := txRepo.lockByUuid(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+		if !versionMatches(before.Version, expectedVersions) {
+			return BusinessErrVersionMismatch
+		}
+		for _, test := range patch.Tests {
+			if !test.Holds(before) {
+				return BusinessErrPatchTestFailed
+			}
+		}
+
+		if len(setParts) == 0 {
+			updatedUser = before
+			return nil
+		}
+
+		setParts = append(setParts, "version = version + 1")
+		args = append(args, before.ID)
+
+		// #nosec G201 -- placeholders are still in place
+		query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d RETURNING %s`,
+			strings.Join(setParts, ", "),
+			sqlPlaceholderIndex,
+			userColumns)
+
+		updatedUser, err = scanUser(txRepo.executor().QueryRowContext(ctx, query, args...))
+		if err != nil {
+			return processConstraintViolations(err)
+		}
+
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionUpdate, before, updatedUser)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	args = append(args, uuid)
+	return updatedUser, nil
+}
+
+func (r *userRepository) ReplaceByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	user model.CreateUserCommand,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	var replacedUser *model.User
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		before, err := txRepo.lockByUuid(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+		if !versionMatches(before.Version, expectedVersions) {
+			return BusinessErrVersionMismatch
+		}
 
-	// #nosec G201 -- placeholders are still in place
-	query := fmt.Sprintf(`UPDATE users SET %s WHERE uuid = $%d`,
-		strings.Join(setParts, ", "),
-		sqlPlaceholderIndex)
+		replacedUser, err = scanUser(txRepo.executor().QueryRowContext(
+			ctx,
+			`UPDATE users SET username = $1, email = $2, full_name = $3, version = version + 1
+			WHERE id = $4 RETURNING `+userColumns,
+			user.Username, user.Email, toNullString(user.FullName), before.ID))
+		if err != nil {
+			return processConstraintViolations(err)
+		}
 
-	result, err := r.db.ExecContext(context.Background(), query, args...)
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionUpdate, before, replacedUser)
+	})
 	if err != nil {
-		return processConstraintViolations(err)
+		return nil, err
+	}
+
+	return replacedUser, nil
+}
+
+// lockByUuid reads a user for update within the ongoing transaction,
+// either among the live users or among the soft-deleted ones.
+func (r *userRepository) lockByUuid(ctx context.Context, uuid uuid.UUID, deleted bool) (*model.User, error) {
+	deletedCondition := "deleted_at IS NULL"
+	if deleted {
+		deletedCondition = "deleted_at IS NOT NULL"
+	}
+
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE uuid = $1 AND `+deletedCondition+` FOR UPDATE`,
+		uuid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
+	}
+
+	return user, err
+}
+
+func versionMatches(version int, expectedVersions []int) bool {
+	if expectedVersions == nil {
+		return true
 	}
 
-	return ensureSomeRowsAffected(result)
+	return slices.Contains(expectedVersions, version)
 }
 
-func (r *userRepository) Create(user dto.UserCreate) (*model.User, error) {
-	var fullNameValue sql.NullString
-	if user.FullName != nil {
-		fullNameValue = sql.NullString{
-			String: *user.FullName,
-			Valid:  true,
+func (r *userRepository) Create(ctx context.Context, user model.CreateUserCommand, audit model.AuditContext) (*model.User, error) {
+	const query = `
+        INSERT INTO users (username, email, full_name)
+        VALUES ($1, $2, $3)
+        RETURNING ` + userColumns
+
+	var createdUser *model.User
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		var err error
+		createdUser, err = scanUser(txRepo.executor().QueryRowContext(
+			ctx,
+			query,
+			user.Username, user.Email, toNullString(user.FullName),
+		))
+		if err != nil {
+			return processConstraintViolations(err)
 		}
+
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionCreate, nil, createdUser)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return createdUser, nil
+}
+
+func (r *userRepository) UpsertByUsername(
+	ctx context.Context,
+	user model.CreateUserCommand,
+	audit model.AuditContext,
+) (*model.User, bool, error) {
 	const query = `
         INSERT INTO users (username, email, full_name)
         VALUES ($1, $2, $3)
-        RETURNING id, uuid, username, email, full_name
-    `
+        ON CONFLICT (lower(username)) WHERE deleted_at IS NULL DO UPDATE
+        SET email = EXCLUDED.email, full_name = EXCLUDED.full_name, version = users.version + 1
+        RETURNING ` + userColumns + `, xmax = 0`
+
+	var upsertedUser *model.User
+	var created bool
+	err := r.inTransaction(ctx, func(txRepo *userRepository) error {
+		before, err := txRepo.lockByUsername(ctx, user.Username)
+		if err != nil && !errors.Is(err, BusinessErrNoUsers) {
+			return err
+		}
+
+		upsertedUser, created, err = scanUpsertedUser(txRepo.executor().QueryRowContext(
+			ctx,
+			query,
+			user.Username, user.Email, toNullString(user.FullName)))
+		if err != nil {
+			return processConstraintViolations(err)
+		}
+
+		if created {
+			return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionCreate, nil, upsertedUser)
+		}
+		// before stays nil if the user has been created concurrently after the lock has been attempted
+		return insertAuditEntry(ctx, txRepo.executor(), audit, model.AuditActionUpdate, before, upsertedUser)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return upsertedUser, created, nil
+}
+
+func (r *userRepository) lockByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := scanUser(r.executor().QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE lower(username) = lower($1) AND deleted_at IS NULL FOR UPDATE`,
+		username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, BusinessErrNoUsers
+	}
+
+	return user, err
+}
 
-	var createdUser model.User
-	err := r.db.QueryRowContext(
-		context.Background(),
-		query,
-		user.Username, user.Email, fullNameValue,
-	).Scan(
-		&createdUser.ID, &createdUser.UUID, &createdUser.Username, &createdUser.Email, &createdUser.FullName)
+func scanUpsertedUser(row rowScanner) (*model.User, bool, error) {
+	var user model.User
+	var created bool
+	if err := row.Scan(
+		&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName,
+		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.Version, &created); err != nil {
+		return nil, false, err
+	}
+
+	return &user, created, nil
+}
+
+func toNullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *value, Valid: true}
+}
 
+func scanUser(row rowScanner) (*model.User, error) {
+	var user model.User
+	if err := row.Scan(
+		&user.ID, &user.UUID, &user.Username, &user.Email, &user.FullName,
+		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.Version); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func scanUsers(rows *sql.Rows, err error) ([]model.User, error) {
 	if err != nil {
-		return nil, processConstraintViolations(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return &createdUser, nil
+	return users, nil
 }
 
 func processConstraintViolations(err error) error {
@@ -193,9 +489,9 @@ func processConstraintViolations(err error) error {
 		switch pgErr.Code {
 		case uniqueConstraintViolationCode:
 			switch pgErr.Constraint {
-			case "users_username_key":
+			case "users_username_lower_key":
 				return BusinessErrUsernameTaken
-			case "users_email_key":
+			case "users_email_lower_key":
 				return BusinessErrEmailTaken
 			default:
 				return BusinessErrUnknownConflict
@@ -205,15 +501,3 @@ func processConstraintViolations(err error) error {
 
 	return err
 }
-
-func ensureSomeRowsAffected(result sql.Result) error {
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return BusinessErrNoUsers
-	}
-
-	return nil
-}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/controller/dto"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/pkg/validation"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+type APIKeyService interface {
+	Mint(ctx context.Context, key dto.APIKeyCreate) (*model.MintedAPIKey, error)
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+	Rotate(ctx context.Context, uuid uuid.UUID, gracePeriod time.Duration) (*model.MintedAPIKey, error)
+	Revoke(ctx context.Context, uuid uuid.UUID) error
+	// Authenticate returns the active key matching the raw key, or nil if there is none.
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+	HasActiveKeys(ctx context.Context) (bool, error)
+}
+
+type apiKeyService struct {
+	repo       repository.APIKeyRepository
+	userPolicy *model.UserPolicy
+}
+
+// a raw key reads cruder_<prefix id>_<secret>, the part before the secret being its stored prefix
+const apiKeyPrefixLabel = "cruder_"
+const apiKeyPrefixIDBytes = 6
+const apiKeySecretBytes = 32
+const apiKeySaltBytes = 16
+
+var rawAPIKeyPattern = regexp.MustCompile(`^(` + apiKeyPrefixLabel + `[0-9a-f]{12})_[A-Za-z0-9_-]{43}$`)
+
+// NewAPIKeyService only accepts the roles defined by the user policy, so none without it.
+func NewAPIKeyService(repo repository.APIKeyRepository, userPolicy *model.UserPolicy) APIKeyService {
+	return &apiKeyService{repo: repo, userPolicy: userPolicy}
+}
+
+func (s *apiKeyService) Mint(ctx context.Context, key dto.APIKeyCreate) (*model.MintedAPIKey, error) {
+	var errs validation.Errors
+	errs.Check("name", validation.APIKeyName(key.Name))
+	errs.Check("scopes", validation.Scopes(key.Scopes, model.AllScopes))
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		errs.Check("expires_at", errors.New("must be in the future"))
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	for _, role := range key.Roles {
+		if !s.userPolicy.Defines(role) {
+			return nil, model.NewBusinessError(
+				model.ErrorKindInvalidInput, fmt.Sprintf("the role %q is not defined by the user policy", role))
+		}
+	}
+
+	rawKey, stored := newRawAPIKey()
+	stored.Name = key.Name
+	stored.Scopes = key.Scopes
+	stored.Roles = key.Roles
+	if stored.Roles == nil {
+		stored.Roles = []string{}
+	}
+	if key.ExpiresAt != nil {
+		stored.ExpiresAt = sql.NullTime{Time: *key.ExpiresAt, Valid: true}
+	}
+
+	created, err := s.repo.Create(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MintedAPIKey{APIKey: *created, RawKey: rawKey}, nil
+}
+
+func (s *apiKeyService) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *apiKeyService) Rotate(ctx context.Context, uuid uuid.UUID, gracePeriod time.Duration) (*model.MintedAPIKey, error) {
+	rawKey, stored := newRawAPIKey()
+	rotated, err := s.repo.Rotate(ctx, uuid, stored, gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MintedAPIKey{APIKey: *rotated, RawKey: rawKey}, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, uuid uuid.UUID) error {
+	return s.repo.Revoke(ctx, uuid)
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	match := rawAPIKeyPattern.FindStringSubmatch(rawKey)
+	if match == nil {
+		return nil, nil
+	}
+
+	key, err := s.repo.GetActiveByPrefix(ctx, match[1])
+	if errors.Is(err, repository.BusinessErrNoAPIKeys) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(hashAPIKey(key.Salt, rawKey), key.Hash) != 1 {
+		return nil, nil
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		// the key is valid all the same, so a failed bookkeeping does not fail the request
+		slog.Warn("failed to record the use of the api key", "prefix", key.Prefix, "error", err)
+	}
+
+	return key, nil
+}
+
+func (s *apiKeyService) HasActiveKeys(ctx context.Context) (bool, error) {
+	return s.repo.HasActive(ctx)
+}
+
+// newRawAPIKey generates a raw key along with the key holding its prefix, salt and hash.
+func newRawAPIKey() (string, model.APIKey) {
+	prefix := apiKeyPrefixLabel + hex.EncodeToString(randomBytes(apiKeyPrefixIDBytes))
+	rawKey := prefix + "_" + base64.RawURLEncoding.EncodeToString(randomBytes(apiKeySecretBytes))
+	salt := randomBytes(apiKeySaltBytes)
+
+	return rawKey, model.APIKey{Prefix: prefix, Salt: salt, Hash: hashAPIKey(salt, rawKey)}
+}
+
+func hashAPIKey(salt []byte, rawKey string) []byte {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(rawKey))
+	return hash.Sum(nil)
+}
+
+func randomBytes(size int) []byte {
+	bytes := make([]byte, size)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(bytes)
+	return bytes
+}
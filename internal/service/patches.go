@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"cruder/internal/controller/dto"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/pkg/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const jsonPatchOpAdd = "add"
+const jsonPatchOpReplace = "replace"
+const jsonPatchOpRemove = "remove"
+const jsonPatchOpTest = "test"
+
+var patchableFields = map[string]bool{"username": true, "email": true, "full_name": true}
+
+func fromMergePatch(patch dto.UserMergePatch) (model.PatchUserCommand, error) {
+	var command model.PatchUserCommand
+	var errs validation.Errors
+	for _, field := range slices.Sorted(maps.Keys(patch)) {
+		if !patchableFields[field] {
+			errs.Check(field, errors.New("is not a known field"))
+			continue
+		}
+		value, err := decodePatchValue(patch[field])
+		if err != nil {
+			errs.Check(field, err)
+			continue
+		}
+		errs.Check(field, setPatchField(&command, field, value))
+	}
+
+	return command, errs.Err()
+}
+
+// fromJSONPatch folds the operations into a single command. A test of a field set by an earlier
+// operation is decided right away, the other tests are left to be checked against the stored user.
+func fromJSONPatch(operations []dto.JSONPatchOperation) (model.PatchUserCommand, error) {
+	var command model.PatchUserCommand
+	var errs validation.Errors
+	for i, operation := range operations {
+		pointer := fmt.Sprintf("/%d", i)
+
+		field, found := strings.CutPrefix(operation.Path, "/")
+		if !found || !patchableFields[field] {
+			errs.Check(pointer+"/path", errors.New("must be one of /username, /email, /full_name"))
+			continue
+		}
+
+		switch operation.Op {
+		case jsonPatchOpAdd, jsonPatchOpReplace, jsonPatchOpTest:
+			if operation.Value == nil {
+				errs.Check(pointer+"/value", errors.New("is required"))
+				continue
+			}
+			value, err := decodePatchValue(operation.Value)
+			if err != nil {
+				errs.Check(pointer+"/value", err)
+				continue
+			}
+
+			if operation.Op != jsonPatchOpTest {
+				errs.Check(pointer+"/value", setPatchField(&command, field, value))
+				continue
+			}
+			if current, patched := patchedValue(command, field); !patched {
+				command.Tests = append(command.Tests, model.FieldTest{Field: field, Value: value})
+			} else if !equalPatchValues(current, value) {
+				return command, repository.BusinessErrPatchTestFailed
+			}
+		case jsonPatchOpRemove:
+			if setPatchField(&command, field, nil) != nil {
+				errs.Check(pointer+"/path", errors.New("cannot be removed"))
+			}
+		default:
+			errs.Check(pointer+"/op", errors.New("must be one of add, replace, remove, test"))
+		}
+	}
+
+	return command, errs.Err()
+}
+
+// decodePatchValue accepts a JSON string or null, the latter being returned as nil.
+func decodePatchValue(raw json.RawMessage) (*string, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, errors.New("must be a string or null")
+	}
+
+	return &value, nil
+}
+
+func setPatchField(command *model.PatchUserCommand, field string, value *string) error {
+	switch field {
+	case "username":
+		if value == nil {
+			return errors.New("must not be null")
+		}
+		command.Username = value
+	case "email":
+		if value == nil {
+			return errors.New("must not be null")
+		}
+		command.Email = value
+	case "full_name":
+		if value == nil {
+			command.FullName = model.OptionalNull[string]()
+		} else {
+			command.FullName = model.OptionalOf(*value)
+		}
+	}
+
+	return nil
+}
+
+// patchedValue returns the value the command sets the field to, if it sets the field at all.
+func patchedValue(command model.PatchUserCommand, field string) (*string, bool) {
+	switch field {
+	case "username":
+		return command.Username, command.Username != nil
+	case "email":
+		return command.Email, command.Email != nil
+	case "full_name":
+		return command.FullName.Value, command.FullName.Present
+	default:
+		return nil, false
+	}
+}
+
+func equalPatchValues(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
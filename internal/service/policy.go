@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/controller/dto"
+	"cruder/internal/model"
+	"github.com/google/uuid"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// userPolicyEnforcer checks the actions of the principal found in the context against the policy before handing
+// them over. The principals without roles are given the default ones of the policy.
+type userPolicyEnforcer struct {
+	next   UserService
+	policy *model.UserPolicy
+}
+
+func NewUserPolicyEnforcer(next UserService, policy *model.UserPolicy) UserService {
+	return &userPolicyEnforcer{next: next, policy: policy}
+}
+
+func (e *userPolicyEnforcer) GetAll(
+	ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetAll(ctx, filter, pageRequest)
+}
+
+func (e *userPolicyEnforcer) GetAllDeleted(
+	ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetAllDeleted(ctx, filter, pageRequest)
+}
+
+func (e *userPolicyEnforcer) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetByUsername(ctx, username)
+}
+
+func (e *userPolicyEnforcer) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetByEmail(ctx, email)
+}
+
+func (e *userPolicyEnforcer) Search(
+	ctx context.Context, text string, pageRequest model.PageRequest) (*model.UserSearchPage, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.Search(ctx, text, pageRequest)
+}
+
+func (e *userPolicyEnforcer) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetByID(ctx, id)
+}
+
+func (e *userPolicyEnforcer) GetByUuid(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetByUuid(ctx, uuid)
+}
+
+func (e *userPolicyEnforcer) GetHistoryByUuid(
+	ctx context.Context, uuid uuid.UUID, pageRequest model.PageRequest) (*model.AuditPage, error) {
+	if err := e.check(ctx, model.PolicyActionRead); err != nil {
+		return nil, err
+	}
+	return e.next.GetHistoryByUuid(ctx, uuid, pageRequest)
+}
+
+func (e *userPolicyEnforcer) DeleteByUuid(
+	ctx context.Context, uuid uuid.UUID, expectedVersions []int, audit model.AuditContext) error {
+	if err := e.check(ctx, model.PolicyActionDelete); err != nil {
+		return err
+	}
+	return e.next.DeleteByUuid(ctx, uuid, expectedVersions, audit)
+}
+
+func (e *userPolicyEnforcer) RestoreByUuid(
+	ctx context.Context, uuid uuid.UUID, audit model.AuditContext) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionRestore); err != nil {
+		return nil, err
+	}
+	return e.next.RestoreByUuid(ctx, uuid, audit)
+}
+
+func (e *userPolicyEnforcer) PartiallyUpdateByUuid(
+	ctx context.Context, uuid uuid.UUID, patch dto.UserPatch, expectedVersions []int, audit model.AuditContext,
+) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionUpdate, patchFields(patch)...); err != nil {
+		return nil, err
+	}
+	return e.next.PartiallyUpdateByUuid(ctx, uuid, patch, expectedVersions, audit)
+}
+
+func (e *userPolicyEnforcer) MergePatchByUuid(
+	ctx context.Context, uuid uuid.UUID, patch dto.UserMergePatch, expectedVersions []int, audit model.AuditContext,
+) (*model.User, error) {
+	// The unknown fields are left to the validation of the patch.
+	var fields []string
+	for _, field := range slices.Sorted(maps.Keys(patch)) {
+		if patchableFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	if err := e.check(ctx, model.PolicyActionUpdate, fields...); err != nil {
+		return nil, err
+	}
+	return e.next.MergePatchByUuid(ctx, uuid, patch, expectedVersions, audit)
+}
+
+func (e *userPolicyEnforcer) JSONPatchByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	operations []dto.JSONPatchOperation,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	var fields []string
+	for _, operation := range operations {
+		field, found := strings.CutPrefix(operation.Path, "/")
+		if found && patchableFields[field] && operation.Op != jsonPatchOpTest {
+			fields = append(fields, field)
+		}
+	}
+	if err := e.check(ctx, model.PolicyActionUpdate, fields...); err != nil {
+		return nil, err
+	}
+	return e.next.JSONPatchByUuid(ctx, uuid, operations, expectedVersions, audit)
+}
+
+// ReplaceByUuid needs every field since the omitted ones are cleared.
+func (e *userPolicyEnforcer) ReplaceByUuid(
+	ctx context.Context, uuid uuid.UUID, user dto.UserCreate, expectedVersions []int, audit model.AuditContext,
+) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionUpdate, model.PolicyFields...); err != nil {
+		return nil, err
+	}
+	return e.next.ReplaceByUuid(ctx, uuid, user, expectedVersions, audit)
+}
+
+func (e *userPolicyEnforcer) Create(
+	ctx context.Context, user dto.UserCreate, audit model.AuditContext) (*model.User, error) {
+	if err := e.check(ctx, model.PolicyActionCreate, createFields(user)...); err != nil {
+		return nil, err
+	}
+	return e.next.Create(ctx, user, audit)
+}
+
+// UpsertByUsername needs both the creation and the update, whichever of the two it turns out to be.
+func (e *userPolicyEnforcer) UpsertByUsername(
+	ctx context.Context, username string, user dto.UserUpsert, audit model.AuditContext) (*model.User, bool, error) {
+	creation := dto.UserCreate{Username: username, Email: user.Email, FullName: user.FullName}
+	if err := e.check(ctx, model.PolicyActionCreate, createFields(creation)...); err != nil {
+		return nil, false, err
+	}
+	if err := e.check(ctx, model.PolicyActionUpdate, "email", "full_name"); err != nil {
+		return nil, false, err
+	}
+	return e.next.UpsertByUsername(ctx, username, user, audit)
+}
+
+// ExecuteBatch refuses the whole batch if any of its operations is denied, atomic or not.
+func (e *userPolicyEnforcer) ExecuteBatch(
+	ctx context.Context, operations []dto.UserBatchOperation, atomic bool, audit model.AuditContext,
+) ([]model.UserOperationResult, error) {
+	for i, operation := range operations {
+		var err error
+		switch {
+		case operation.Op == model.UserOperationCreate && operation.User != nil:
+			err = e.check(ctx, model.PolicyActionCreate, createFields(*operation.User)...)
+		case operation.Op == model.UserOperationPatch && operation.Patch != nil:
+			err = e.check(ctx, model.PolicyActionUpdate, patchFields(*operation.Patch)...)
+		case operation.Op == model.UserOperationDelete:
+			err = e.check(ctx, model.PolicyActionDelete)
+		}
+		if err != nil {
+			return nil, &model.BatchOperationError{Index: i, Err: err}
+		}
+	}
+	return e.next.ExecuteBatch(ctx, operations, atomic, audit)
+}
+
+func (e *userPolicyEnforcer) check(ctx context.Context, action string, fields ...string) error {
+	principal, _ := model.PrincipalFromContext(ctx)
+	roles := principal.Roles
+	if len(roles) == 0 {
+		roles = e.policy.DefaultRoles
+	}
+
+	return e.policy.Check(roles, action, fields)
+}
+
+func createFields(user dto.UserCreate) []string {
+	fields := []string{"username", "email"}
+	if user.FullName != nil {
+		fields = append(fields, "full_name")
+	}
+	return fields
+}
+
+func patchFields(patch dto.UserPatch) []string {
+	var fields []string
+	if patch.Username != nil {
+		fields = append(fields, "username")
+	}
+	if patch.Email != nil {
+		fields = append(fields, "email")
+	}
+	if patch.FullName != nil {
+		fields = append(fields, "full_name")
+	}
+	return fields
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"github.com/google/uuid"
+	"log/slog"
+	"time"
+)
+
+const purgerActor = "system:purger"
+
+// DeletedUsersPurger hard-deletes users that have stayed soft-deleted longer than the retention period.
+type DeletedUsersPurger struct {
+	repo      repository.UserRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewDeletedUsersPurger(
+	repo repository.UserRepository,
+	retention time.Duration,
+	interval time.Duration,
+) *DeletedUsersPurger {
+	return &DeletedUsersPurger{repo: repo, retention: retention, interval: interval}
+}
+
+func (p *DeletedUsersPurger) Run(ctx context.Context) {
+	runPeriodically(ctx, p.interval, p.Purge)
+}
+
+func (p *DeletedUsersPurger) Purge(ctx context.Context) {
+	audit := model.AuditContext{Actor: purgerActor, RequestID: uuid.NewString()}
+	purged, err := p.repo.PurgeDeletedBefore(ctx, time.Now().Add(-p.retention), audit)
+	if err != nil {
+		slog.Error("failed to purge deleted users", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("purged deleted users", "count", purged)
+	}
+}
+
+// ExpiredIdempotencyKeysPurger deletes the idempotency keys whose responses are no longer replayed.
+type ExpiredIdempotencyKeysPurger struct {
+	repo     repository.IdempotencyKeyRepository
+	interval time.Duration
+}
+
+func NewExpiredIdempotencyKeysPurger(
+	repo repository.IdempotencyKeyRepository,
+	interval time.Duration,
+) *ExpiredIdempotencyKeysPurger {
+	return &ExpiredIdempotencyKeysPurger{repo: repo, interval: interval}
+}
+
+func (p *ExpiredIdempotencyKeysPurger) Run(ctx context.Context) {
+	runPeriodically(ctx, p.interval, p.Purge)
+}
+
+func (p *ExpiredIdempotencyKeysPurger) Purge(ctx context.Context) {
+	purged, err := p.repo.DeleteExpired(ctx)
+	if err != nil {
+		slog.Error("failed to purge expired idempotency keys", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("purged expired idempotency keys", "count", purged)
+	}
+}
+
+func runPeriodically(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package service
 
-import "cruder/internal/repository"
+import (
+	"cruder/internal/model"
+	"cruder/internal/repository"
+)
 
 type Service struct {
-	Users UserService
+	Users   UserService
+	APIKeys APIKeyService
 }
 
-func NewService(repos *repository.Repository) *Service {
+// NewService enforces the user policy on the user service, unless it is nil.
+func NewService(repos *repository.Repository, userPolicy *model.UserPolicy) *Service {
+	users := NewUserService(repos.Users, repos.AuditLog)
+	if userPolicy != nil {
+		users = NewUserPolicyEnforcer(users, userPolicy)
+	}
+
 	return &Service{
-		Users: NewUserService(repos.Users),
+		Users:   users,
+		APIKeys: NewAPIKeyService(repos.APIKeys, userPolicy),
 	}
 }
@@ -1,57 +1,367 @@
 package service
 
 import (
+	"context"
 	"cruder/internal/controller/dto"
 	"cruder/internal/model"
 	"cruder/internal/repository"
+	"cruder/pkg/validation"
 	"errors"
 	"github.com/google/uuid"
 	"log/slog"
+	"strings"
 )
 
 type UserService interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	DeleteByUuid(uuid uuid.UUID) error
-	PartiallyUpdateByUuid(uuid uuid.UUID, patch dto.UserPatch) error
-	Create(user dto.UserCreate) (*model.User, error)
+	GetAll(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error)
+	GetAllDeleted(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	Search(ctx context.Context, text string, pageRequest model.PageRequest) (*model.UserSearchPage, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUuid(ctx context.Context, uuid uuid.UUID) (*model.User, error)
+	GetHistoryByUuid(ctx context.Context, uuid uuid.UUID, pageRequest model.PageRequest) (*model.AuditPage, error)
+	DeleteByUuid(ctx context.Context, uuid uuid.UUID, expectedVersions []int, audit model.AuditContext) error
+	RestoreByUuid(ctx context.Context, uuid uuid.UUID, audit model.AuditContext) (*model.User, error)
+	PartiallyUpdateByUuid(
+		ctx context.Context, uuid uuid.UUID, patch dto.UserPatch, expectedVersions []int, audit model.AuditContext) (*model.User, error)
+	MergePatchByUuid(
+		ctx context.Context, uuid uuid.UUID, patch dto.UserMergePatch, expectedVersions []int, audit model.AuditContext,
+	) (*model.User, error)
+	JSONPatchByUuid(
+		ctx context.Context,
+		uuid uuid.UUID,
+		operations []dto.JSONPatchOperation,
+		expectedVersions []int,
+		audit model.AuditContext,
+	) (*model.User, error)
+	ReplaceByUuid(
+		ctx context.Context, uuid uuid.UUID, user dto.UserCreate, expectedVersions []int, audit model.AuditContext,
+	) (*model.User, error)
+	Create(ctx context.Context, user dto.UserCreate, audit model.AuditContext) (*model.User, error)
+	UpsertByUsername(
+		ctx context.Context, username string, user dto.UserUpsert, audit model.AuditContext) (*model.User, bool, error)
+	// ExecuteBatch runs the operations in order. An atomic batch either succeeds as a whole or fails
+	// with a *model.BatchOperationError, otherwise every operation succeeds or fails on its own.
+	ExecuteBatch(
+		ctx context.Context, operations []dto.UserBatchOperation, atomic bool, audit model.AuditContext,
+	) ([]model.UserOperationResult, error)
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	auditRepo repository.AuditLogRepository
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo repository.UserRepository, auditRepo repository.AuditLogRepository) UserService {
+	return &userService{repo: repo, auditRepo: auditRepo}
 }
 
-func (s *userService) GetAll() ([]model.User, error) {
-	return s.repo.GetAll()
+func (s *userService) GetAll(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error) {
+	return s.repo.GetAll(ctx, filter, pageRequest)
 }
 
-func (s *userService) GetByUsername(username string) (*model.User, error) {
-	user, err := s.repo.GetByUsername(username)
+func (s *userService) GetAllDeleted(ctx context.Context, filter model.UserFilter, pageRequest model.PageRequest) (*model.UserPage, error) {
+	filter.Deleted = true
+	return s.repo.GetAll(ctx, filter, pageRequest)
+}
+
+func (s *userService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.repo.GetByUsername(ctx, username)
 
 	return getSingleUser(user, err)
 }
 
-func (s *userService) GetByID(id int64) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.repo.GetByEmail(ctx, normalize(email))
 
 	return getSingleUser(user, err)
 }
 
-func (s *userService) DeleteByUuid(uuid uuid.UUID) error {
-	return s.repo.DeleteByUuid(uuid)
+func (s *userService) Search(ctx context.Context, text string, pageRequest model.PageRequest) (*model.UserSearchPage, error) {
+	return s.repo.Search(ctx, text, pageRequest)
+}
+
+func (s *userService) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+
+	return getSingleUser(user, err)
+}
+
+func (s *userService) GetByUuid(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	user, err := s.repo.GetByUuid(ctx, uuid)
+
+	return getSingleUser(user, err)
+}
+
+func (s *userService) GetHistoryByUuid(ctx context.Context, uuid uuid.UUID, pageRequest model.PageRequest) (*model.AuditPage, error) {
+	return s.auditRepo.GetByUserUuid(ctx, uuid, pageRequest)
+}
+
+func (s *userService) DeleteByUuid(ctx context.Context, uuid uuid.UUID, expectedVersions []int, audit model.AuditContext) error {
+	return s.repo.DeleteByUuid(ctx, uuid, expectedVersions, audit)
+}
+
+func (s *userService) RestoreByUuid(ctx context.Context, uuid uuid.UUID, audit model.AuditContext) (*model.User, error) {
+	user, err := s.repo.RestoreByUuid(ctx, uuid, audit)
+
+	return getSingleUser(user, err)
+}
+
+func (s *userService) PartiallyUpdateByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	patch dto.UserPatch,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	return s.patch(ctx, uuid, toPatchUserCommand(patch), expectedVersions, audit)
+}
+
+func (s *userService) MergePatchByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	patch dto.UserMergePatch,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	command, err := fromMergePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.patch(ctx, uuid, command, expectedVersions, audit)
+}
+
+func (s *userService) JSONPatchByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	operations []dto.JSONPatchOperation,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	command, err := fromJSONPatch(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.patch(ctx, uuid, command, expectedVersions, audit)
+}
+
+func (s *userService) patch(
+	ctx context.Context,
+	uuid uuid.UUID,
+	command model.PatchUserCommand,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	if err := preparePatchUserCommand(&command); err != nil {
+		return nil, err
+	}
+
+	return s.repo.PartiallyUpdateByUUID(ctx, uuid, command, expectedVersions, audit)
 }
 
-func (s *userService) PartiallyUpdateByUuid(uuid uuid.UUID, patch dto.UserPatch) error {
-	return s.repo.PartiallyUpdateByUUID(uuid, patch)
+func (s *userService) ReplaceByUuid(
+	ctx context.Context,
+	uuid uuid.UUID,
+	user dto.UserCreate,
+	expectedVersions []int,
+	audit model.AuditContext,
+) (*model.User, error) {
+	command := toCreateUserCommand(user)
+	if err := prepareCreateUserCommand(&command); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ReplaceByUuid(ctx, uuid, command, expectedVersions, audit)
+}
+
+func (s *userService) Create(ctx context.Context, user dto.UserCreate, audit model.AuditContext) (*model.User, error) {
+	command := toCreateUserCommand(user)
+	if err := prepareCreateUserCommand(&command); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, command, audit)
+}
+
+func (s *userService) UpsertByUsername(
+	ctx context.Context,
+	username string,
+	user dto.UserUpsert,
+	audit model.AuditContext,
+) (*model.User, bool, error) {
+	command := model.CreateUserCommand{Username: username, Email: user.Email, FullName: user.FullName}
+	if err := prepareCreateUserCommand(&command); err != nil {
+		return nil, false, err
+	}
+
+	return s.repo.UpsertByUsername(ctx, command, audit)
+}
+
+func (s *userService) ExecuteBatch(
+	ctx context.Context,
+	operations []dto.UserBatchOperation,
+	atomic bool,
+	audit model.AuditContext,
+) ([]model.UserOperationResult, error) {
+	results := make([]model.UserOperationResult, len(operations))
+	if !atomic {
+		for i, operation := range operations {
+			results[i] = executeOperation(ctx, s.repo, operation, audit)
+		}
+		return results, nil
+	}
+
+	err := s.repo.WithinTransaction(ctx, func(txRepo repository.UserRepository) error {
+		for i, operation := range operations {
+			results[i] = executeOperation(ctx, txRepo, operation, audit)
+			if results[i].Err != nil {
+				return &model.BatchOperationError{Index: i, Err: results[i].Err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func executeOperation(
+	ctx context.Context,
+	repo repository.UserRepository,
+	operation dto.UserBatchOperation,
+	audit model.AuditContext,
+) model.UserOperationResult {
+	command, err := toUserOperation(operation)
+	if err != nil {
+		return model.UserOperationResult{Kind: operation.Op, Err: err}
+	}
+
+	result := model.UserOperationResult{Kind: command.Kind}
+	switch command.Kind {
+	case model.UserOperationCreate:
+		result.User, result.Err = repo.Create(ctx, command.Create, audit)
+	case model.UserOperationPatch:
+		result.User, result.Err = repo.PartiallyUpdateByUUID(ctx, command.UUID, command.Patch, command.ExpectedVersions, audit)
+	case model.UserOperationDelete:
+		result.Err = repo.DeleteByUuid(ctx, command.UUID, command.ExpectedVersions, audit)
+	}
+
+	return result
+}
+
+func toUserOperation(operation dto.UserBatchOperation) (model.UserOperation, error) {
+	command := model.UserOperation{Kind: operation.Op}
+	if operation.Version != nil {
+		command.ExpectedVersions = []int{*operation.Version}
+	}
+
+	var errs validation.Errors
+	switch operation.Op {
+	case model.UserOperationCreate:
+		if operation.User == nil {
+			errs.Check("user", errors.New("is required"))
+			break
+		}
+		command.Create = toCreateUserCommand(*operation.User)
+		errs.CheckNested("user", prepareCreateUserCommand(&command.Create))
+	case model.UserOperationPatch, model.UserOperationDelete:
+		if operation.UUID == nil {
+			errs.Check("uuid", errors.New("is required"))
+		} else {
+			command.UUID = *operation.UUID
+		}
+		if operation.Op == model.UserOperationDelete {
+			break
+		}
+		if operation.Patch == nil {
+			errs.Check("patch", errors.New("is required"))
+			break
+		}
+		command.Patch = toPatchUserCommand(*operation.Patch)
+		errs.CheckNested("patch", preparePatchUserCommand(&command.Patch))
+	default:
+		errs.Check("op", errors.New("must be one of create, patch, delete"))
+	}
+
+	return command, errs.Err()
+}
+
+func toCreateUserCommand(user dto.UserCreate) model.CreateUserCommand {
+	return model.CreateUserCommand{
+		Username: user.Username,
+		Email:    user.Email,
+		FullName: user.FullName,
+	}
+}
+
+func toPatchUserCommand(patch dto.UserPatch) model.PatchUserCommand {
+	command := model.PatchUserCommand{
+		Username: patch.Username,
+		Email:    patch.Email,
+	}
+	if patch.FullName != nil && patch.FullName.Value == nil {
+		command.FullName = model.OptionalNull[string]()
+	} else if patch.FullName != nil {
+		command.FullName = model.OptionalOf(*patch.FullName.Value)
+	}
+
+	return command
+}
+
+// prepareCreateUserCommand normalizes the command in place and validates it.
+func prepareCreateUserCommand(command *model.CreateUserCommand) error {
+	command.Username = normalize(command.Username)
+	command.Email = normalize(command.Email)
+
+	var errs validation.Errors
+	errs.Check("username", validation.Username(command.Username))
+	errs.Check("email", validation.Email(command.Email))
+	if command.FullName != nil {
+		errs.Check("full_name", validation.FullName(*command.FullName))
+	}
+
+	return errs.Err()
+}
+
+// preparePatchUserCommand normalizes the command in place and validates it.
+func preparePatchUserCommand(command *model.PatchUserCommand) error {
+	if command.Username != nil {
+		username := normalize(*command.Username)
+		command.Username = &username
+	}
+	if command.Email != nil {
+		email := normalize(*command.Email)
+		command.Email = &email
+	}
+	for i, test := range command.Tests {
+		if test.Value != nil && (test.Field == "username" || test.Field == "email") {
+			value := normalize(*test.Value)
+			command.Tests[i].Value = &value
+		}
+	}
+
+	var errs validation.Errors
+	if command.Username != nil {
+		errs.Check("username", validation.Username(*command.Username))
+	}
+	if command.Email != nil {
+		errs.Check("email", validation.Email(*command.Email))
+	}
+	if command.FullName.Present && !command.FullName.IsNull() {
+		errs.Check("full_name", validation.FullName(*command.FullName.Value))
+	}
+
+	return errs.Err()
 }
 
-func (s *userService) Create(user dto.UserCreate) (*model.User, error) {
-	return s.repo.Create(user)
+// normalize lower-cases usernames and emails, which are unique regardless of case.
+func normalize(value string) string {
+	return strings.ToLower(value)
 }
 
 func getSingleUser(user *model.User, err error) (*model.User, error) {
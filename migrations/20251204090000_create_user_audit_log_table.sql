@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    request_id VARCHAR(100) NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- no foreign key on purpose: the trail outlives purged users
+CREATE INDEX user_audit_log_user_uuid_idx ON user_audit_log(user_uuid, id);
+
+CREATE OR REPLACE FUNCTION forbid_audit_log_changes() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'user_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON user_audit_log
+    FOR EACH ROW
+    EXECUTE FUNCTION forbid_audit_log_changes();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_audit_log;
+
+DROP FUNCTION IF EXISTS forbid_audit_log_changes();
+-- +goose StatementEnd
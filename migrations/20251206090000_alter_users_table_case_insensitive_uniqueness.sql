@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+-- of the live users sharing a username or an email regardless of case, only the oldest one stays live
+WITH duplicates AS (
+    UPDATE users SET deleted_at = now(), version = version + 1
+    WHERE deleted_at IS NULL AND EXISTS (
+        SELECT 1 FROM users AS older
+        WHERE older.deleted_at IS NULL
+          AND older.id < users.id
+          AND (lower(older.username) = lower(users.username) OR lower(older.email) = lower(users.email))
+    )
+    RETURNING uuid, deleted_at
+)
+INSERT INTO user_audit_log (user_uuid, action, actor, request_id, changes)
+SELECT uuid, 'delete', 'system:migration', '20251206090000',
+       jsonb_build_object('deleted_at', jsonb_build_object('before', NULL, 'after', to_jsonb(deleted_at)))
+FROM duplicates;
+
+UPDATE users SET username = lower(username), email = lower(email), version = version + 1
+WHERE username <> lower(username) OR email <> lower(email);
+
+CREATE UNIQUE INDEX users_username_lower_key ON users(lower(username)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_lower_key ON users(lower(email)) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_username_lower_key;
+DROP INDEX IF EXISTS users_email_lower_key;
+
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- back the word similarity operator used by the user search
+CREATE INDEX users_username_trgm_idx ON users USING GIN (username gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX users_email_trgm_idx ON users USING GIN (email gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX users_full_name_trgm_idx ON users USING GIN (full_name gin_trgm_ops) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_full_name_trgm_idx;
+DROP INDEX IF EXISTS users_email_trgm_idx;
+DROP INDEX IF EXISTS users_username_trgm_idx;
+
+DROP EXTENSION IF EXISTS pg_trgm;
+-- +goose StatementEnd
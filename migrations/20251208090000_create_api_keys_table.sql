@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    -- the public start of the raw key, which identifies it and is never secret
+    prefix VARCHAR(32) NOT NULL,
+    -- only the salted SHA-256 of the raw key is stored, the raw key is shown once when minted
+    salt BYTEA NOT NULL,
+    hash BYTEA NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    -- may lie in the future for a key rotated with a grace period
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX api_keys_uuid_idx ON api_keys(uuid);
+CREATE UNIQUE INDEX api_keys_prefix_idx ON api_keys(prefix);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
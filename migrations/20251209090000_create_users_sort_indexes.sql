@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- back the keyset pagination, which orders by one of the sortable columns and then by id
+CREATE INDEX users_username_id_idx ON users (username, id) WHERE deleted_at IS NULL;
+CREATE INDEX users_email_id_idx ON users (email, id) WHERE deleted_at IS NULL;
+CREATE INDEX users_created_at_id_idx ON users (created_at, id) WHERE deleted_at IS NULL;
+-- NULLs come last in both directions, so a backward scan of the ascending index cannot serve the descending order
+CREATE INDEX users_full_name_id_idx ON users (full_name NULLS LAST, id) WHERE deleted_at IS NULL;
+CREATE INDEX users_full_name_desc_id_idx ON users (full_name DESC NULLS LAST, id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_full_name_desc_id_idx;
+DROP INDEX IF EXISTS users_full_name_id_idx;
+DROP INDEX IF EXISTS users_created_at_id_idx;
+DROP INDEX IF EXISTS users_email_id_idx;
+DROP INDEX IF EXISTS users_username_id_idx;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the lease of a claim being processed, past which a retry may take the claim over
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- room for the principal names, like the ones of the stored API keys made of their name and prefix
+ALTER TABLE user_audit_log ALTER COLUMN actor TYPE VARCHAR(255);
+ALTER TABLE idempotency_keys ALTER COLUMN actor TYPE VARCHAR(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys ALTER COLUMN actor TYPE VARCHAR(100);
+ALTER TABLE user_audit_log ALTER COLUMN actor TYPE VARCHAR(100);
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the roles of the user policy, a key without any gets its default roles
+ALTER TABLE api_keys ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE api_keys DROP COLUMN IF EXISTS roles;
+-- +goose StatementEnd
@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// KeySet holds the signature verification keys of a JWKS (RFC 7517) by key id.
+type KeySet struct {
+	keys map[string]publicKey
+}
+
+type publicKey struct {
+	key crypto.PublicKey
+	// alg is the only algorithm the key may be used with, any fitting one if empty
+	alg string
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// ParseKeySet reads the RSA and EC signature keys of a JWKS document, the other keys are ignored.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	set := &KeySet{keys: map[string]publicKey{}}
+	for i, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %d: %w", i, err)
+		}
+		if _, duplicate := set.keys[jwk.Kid]; duplicate {
+			return nil, fmt.Errorf("invalid JWKS: key id %q is used twice", jwk.Kid)
+		}
+		set.keys[jwk.Kid] = publicKey{key: key, alg: jwk.Alg}
+	}
+
+	return set, nil
+}
+
+// lookup finds the key by id, a token without key id can only be verified by a set of a single key.
+func (s *KeySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, found := s.keys[kid]
+	return key, found
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	curve, known := curves[k.Crv]
+	if !known {
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	// the coordinates have the fixed size of the curve, ecdsa.Verify rejects the points off the curve
+	size := (curve.Params().BitSize + 7) / 8
+	x, err := decodeBigInt(k.X)
+	if err != nil || len(k.X) != base64.RawURLEncoding.EncodedLen(size) {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil || len(k.Y) != base64.RawURLEncoding.EncodedLen(size) {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) == 0 {
+		return nil, errors.New("empty value")
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
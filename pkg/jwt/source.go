@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minReloadInterval keeps tokens signed by unknown keys from reloading the key set at every request.
+const minReloadInterval = time.Minute
+
+// loadRetryInterval is how long a failed load is not retried, the previous keys being served meanwhile.
+const loadRetryInterval = 10 * time.Second
+const maxKeySetSize = 1 << 20
+
+// KeySource loads a key set from a file or an http(s) URL on first use, then reloads it once
+// the refresh interval has elapsed or sooner when a token refers to an unknown key.
+type KeySource struct {
+	location        string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	mu       sync.Mutex
+	keys     *KeySet
+	loadedAt time.Time
+	// loading is closed once the load in progress, if any, is over.
+	loading chan struct{}
+	err     error
+	retryAt time.Time
+}
+
+func NewKeySource(location string, refreshInterval time.Duration) *KeySource {
+	return &KeySource{
+		location:        location,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key only waits for a load when there is no key set yet or the kid is unknown, a due refresh
+// runs in the background while the current keys are still served.
+func (s *KeySource) key(ctx context.Context, kid string) (publicKey, bool, error) {
+	s.mu.Lock()
+	var key publicKey
+	found := false
+	if s.keys != nil {
+		key, found = s.keys.lookup(kid)
+	}
+	now := time.Now()
+	age := now.Sub(s.loadedAt)
+	due := s.keys == nil || (s.refreshInterval > 0 && age >= s.refreshInterval) || (!found && age >= minReloadInterval)
+	var loading chan struct{}
+	if due && !now.Before(s.retryAt) {
+		loading = s.startLoad()
+	}
+	keys, err := s.keys, s.err
+	s.mu.Unlock()
+
+	if found || loading == nil {
+		if keys == nil {
+			return publicKey{}, false, err
+		}
+		return key, found, nil
+	}
+
+	select {
+	case <-loading:
+	case <-ctx.Done():
+		return publicKey{}, false, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		return publicKey{}, false, s.err
+	}
+	key, found = s.keys.lookup(kid)
+	return key, found, nil
+}
+
+// startLoad must be called with the lock held. The load is shared by all the callers and
+// outlives any of their requests.
+func (s *KeySource) startLoad() chan struct{} {
+	if s.loading == nil {
+		s.loading = make(chan struct{})
+		go s.load()
+	}
+	return s.loading
+}
+
+func (s *KeySource) load() {
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = err
+		s.retryAt = time.Now().Add(loadRetryInterval)
+		if s.keys != nil {
+			slog.Error("failed to reload the JWKS, keeping the previous keys", "error", err)
+		}
+	} else {
+		s.keys = keys
+		s.loadedAt = time.Now()
+		s.err = nil
+		s.retryAt = time.Time{}
+	}
+	close(s.loading)
+	s.loading = nil
+}
+
+func (s *KeySource) fetch() (*KeySet, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the JWKS from %s: %w", s.location, err)
+	}
+
+	return ParseKeySet(data)
+}
+
+func (s *KeySource) read() ([]byte, error) {
+	if !strings.HasPrefix(s.location, "https://") && !strings.HasPrefix(s.location, "http://") {
+		return os.ReadFile(s.location)
+	}
+
+	response, err := s.client.Get(s.location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxKeySetSize))
+}
@@ -0,0 +1,187 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is wrapped by every error due to the token itself rather than to the key source.
+var ErrInvalidToken = errors.New("invalid token")
+
+// defaultLeeway absorbs the clock skew between the issuer and this service.
+const defaultLeeway = time.Minute
+
+var hashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+var curveByAlgorithm = map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Claims are the registered claims along with the OAuth scope ones and the roles. Scope is space-delimited, Scp is a list.
+type Claims struct {
+	Issuer    string     `json:"iss"`
+	Subject   string     `json:"sub"`
+	Audience  Audience   `json:"aud"`
+	ExpiresAt *Timestamp `json:"exp"`
+	NotBefore *Timestamp `json:"nbf"`
+	Scope     string     `json:"scope"`
+	Scp       []string   `json:"scp"`
+	Roles     []string   `json:"roles"`
+}
+
+// Audience accepts both the single string and the array forms of the aud claim.
+type Audience []string
+
+// Timestamp is a NumericDate, seconds since the epoch.
+type Timestamp float64
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+func (t Timestamp) Time() time.Time {
+	seconds, fraction := math.Modf(float64(t))
+	return time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+}
+
+// Scopes merges the scope and scp claims.
+func (c *Claims) Scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scp...)
+}
+
+// Verifier checks the signature of RS* and ES* tokens and their iss, aud, exp and nbf claims.
+type Verifier struct {
+	keys     *KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+func NewVerifier(keys *KeySource, issuer string, audience string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience, leeway: defaultLeeway, now: time.Now}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var tokenHeader header
+	if err := decodeSegment(parts[0], &tokenHeader); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	hash, supported := hashes[tokenHeader.Alg]
+	if !supported {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, tokenHeader.Alg)
+	}
+
+	key, found, err := v.keys.key(ctx, tokenHeader.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if !found || (key.alg != "" && key.alg != tokenHeader.Alg) {
+		return nil, fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(tokenHeader.Alg, key.key, hash, digest.Sum(nil), signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.validate(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	now := v.now()
+	switch {
+	case claims.Issuer != v.issuer:
+		return errors.New("unexpected issuer")
+	case !slices.Contains(claims.Audience, v.audience):
+		return errors.New("unexpected audience")
+	case claims.ExpiresAt == nil:
+		return errors.New("no expiration time")
+	case !now.Before(claims.ExpiresAt.Time().Add(v.leeway)):
+		return errors.New("expired")
+	case claims.NotBefore != nil && now.Add(v.leeway).Before(claims.NotBefore.Time()):
+		return errors.New("not valid yet")
+	case claims.Subject == "":
+		return errors.New("no subject")
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, hash crypto.Hash, digest []byte, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		if curveByAlgorithm[alg] != key.Curve.Params().Name {
+			return false
+		}
+		// the signature is the concatenation of r and s, each of the size of the curve
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, target any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, target)
+}
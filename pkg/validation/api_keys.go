@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxAPIKeyNameLength = 100
+
+func APIKeyName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("must not be blank")
+	}
+	if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return fmt.Errorf("must be at most %d characters long", maxAPIKeyNameLength)
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return errors.New("must not contain control characters")
+	}
+
+	return nil
+}
+
+// Scopes accepts a non-empty list of distinct scopes taken from the known ones.
+func Scopes(scopes []string, known []string) error {
+	if len(scopes) == 0 {
+		return errors.New("must not be empty")
+	}
+	for i, scope := range scopes {
+		if !slices.Contains(known, scope) {
+			return fmt.Errorf("must be among %s", strings.Join(known, ", "))
+		}
+		if slices.Contains(scopes[:i], scope) {
+			return errors.New("must not contain duplicates")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// Errors collects the violations of all fields of a single input.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Check records err as a violation of field, a nil err is ignored.
+func (e *Errors) Check(field string, err error) {
+	if err != nil {
+		*e = append(*e, FieldError{Field: field, Message: err.Error()})
+	}
+}
+
+// CheckNested records the violations of a nested input under the field prefix.
+func (e *Errors) CheckNested(prefix string, err error) {
+	var nested Errors
+	if !errors.As(err, &nested) {
+		e.Check(prefix, err)
+		return
+	}
+
+	for _, fieldError := range nested {
+		*e = append(*e, FieldError{Field: prefix + "." + fieldError.Field, Message: fieldError.Message})
+	}
+}
+
+// Err returns the collected violations, or nil if there are none.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
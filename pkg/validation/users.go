@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const minUsernameLength = 3
+const maxUsernameLength = 50
+const maxEmailLength = 100
+const maxFullNameLength = 100
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func Username(username string) error {
+	length := utf8.RuneCountInString(username)
+	if length < minUsernameLength || length > maxUsernameLength {
+		return fmt.Errorf("must be between %d and %d characters long", minUsernameLength, maxUsernameLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return errors.New("may only contain letters, digits, '_', '.' and '-'")
+	}
+
+	return nil
+}
+
+// Email accepts a bare RFC 5322 address, without a display name or angle brackets.
+func Email(email string) error {
+	if utf8.RuneCountInString(email) > maxEmailLength {
+		return fmt.Errorf("must be at most %d characters long", maxEmailLength)
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return errors.New("must be a valid email address")
+	}
+
+	return nil
+}
+
+func FullName(fullName string) error {
+	if strings.TrimSpace(fullName) == "" {
+		return errors.New("must not be blank")
+	}
+	if utf8.RuneCountInString(fullName) > maxFullNameLength {
+		return fmt.Errorf("must be at most %d characters long", maxFullNameLength)
+	}
+	if strings.ContainsFunc(fullName, unicode.IsControl) {
+		return errors.New("must not contain control characters")
+	}
+
+	return nil
+}